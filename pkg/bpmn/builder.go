@@ -0,0 +1,343 @@
+/*
+ * Copyright (c) 2023 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bpmn
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"github.com/SENERGY-Platform/models/go/models"
+	"sort"
+	"strings"
+)
+
+const ControllingFunctionRdfType = "https://senergy.infai.org/ontology/ControllingFunction"
+const MeasuringFunctionRdfType = "https://senergy.infai.org/ontology/MeasuringFunction"
+
+type NodeKind string
+
+const (
+	StartEventKind            NodeKind = "startEvent"
+	ConditionalStartEventKind NodeKind = "conditionalStartEvent"
+	ServiceTaskKind           NodeKind = "serviceTask"
+	ScriptTaskKind            NodeKind = "scriptTask"
+	ExclusiveGatewayKind      NodeKind = "exclusiveGateway"
+	ParallelGatewayKind       NodeKind = "parallelGateway"
+	TimerEventKind            NodeKind = "intermediateTimerEvent"
+	EndEventKind              NodeKind = "endEvent"
+)
+
+type Node struct {
+	Id               string
+	Name             string
+	Kind             NodeKind
+	Task             *TaskInfo
+	ConditionalEvent *ConditionalEventInfo
+	Script           string //javascript of script tasks
+	DefaultFlow      string //flow id, used by exclusive gateways if no condition matches
+	TimerDuration    string //iso 8601 duration of intermediate timer events
+	StartParameters  []StartParameterInfo
+}
+
+// StartParameterInfo describes a string form field of a start event; its value is set as process variable with the same id
+type StartParameterInfo struct {
+	Id      string
+	Label   string
+	Default string
+}
+
+// TaskInfo describes a device task; controlling tasks send a command, measuring tasks read a value.
+// Function, DeviceClass and Aspect are optional; if set, they are written into the task payload instead of only the ids, like the process designer does.
+type TaskInfo struct {
+	FunctionId       string
+	DeviceClassId    string
+	AspectId         string
+	Function         *models.Function
+	DeviceClass      *models.DeviceClass
+	Aspect           *models.Aspect
+	CharacteristicId string
+	Retries          int
+	Measuring        bool
+	PreferEvent      bool              //measuring tasks may use the last event value instead of a device request
+	Outputs          map[string]string //process variable name -> expression; e.g. "${outputs}" for the read value of measuring tasks
+}
+
+// ConditionalEventInfo describes a start event, triggered by device data matching the script
+type ConditionalEventInfo struct {
+	FunctionId        string
+	AspectId          string
+	CharacteristicId  string
+	Script            string
+	ValueVariableName string
+	Qos               int
+}
+
+type Flow struct {
+	Id        string
+	Source    string
+	Target    string
+	Condition string //expression, evaluated by exclusive gateways
+}
+
+// Builder creates bpmn xml and a matching diagram for canary processes
+type Builder struct {
+	processId string
+	nodes     []Node
+	flows     []Flow
+}
+
+func NewBuilder(processId string) *Builder {
+	return &Builder{processId: processId}
+}
+
+func (this *Builder) ProcessId() string {
+	return this.processId
+}
+
+func (this *Builder) Nodes() []Node {
+	return this.nodes
+}
+
+// StartEvent adds a plain start event; parameters are listed as start parameters of the deployment
+func (this *Builder) StartEvent(id string, parameters ...StartParameterInfo) string {
+	this.nodes = append(this.nodes, Node{Id: id, Kind: StartEventKind, StartParameters: parameters})
+	return id
+}
+
+func (this *Builder) ConditionalStartEvent(id string, name string, event ConditionalEventInfo) string {
+	this.nodes = append(this.nodes, Node{Id: id, Name: name, Kind: ConditionalStartEventKind, ConditionalEvent: &event})
+	return id
+}
+
+func (this *Builder) ServiceTask(id string, name string, task TaskInfo) string {
+	this.nodes = append(this.nodes, Node{Id: id, Name: name, Kind: ServiceTaskKind, Task: &task})
+	return id
+}
+
+func (this *Builder) ScriptTask(id string, name string, script string) string {
+	this.nodes = append(this.nodes, Node{Id: id, Name: name, Kind: ScriptTaskKind, Script: script})
+	return id
+}
+
+func (this *Builder) ExclusiveGateway(id string) string {
+	this.nodes = append(this.nodes, Node{Id: id, Kind: ExclusiveGatewayKind})
+	return id
+}
+
+func (this *Builder) ParallelGateway(id string) string {
+	this.nodes = append(this.nodes, Node{Id: id, Kind: ParallelGatewayKind})
+	return id
+}
+
+// TimerEvent waits for the iso 8601 duration (e.g. PT10S) before continuing
+func (this *Builder) TimerEvent(id string, name string, duration string) string {
+	this.nodes = append(this.nodes, Node{Id: id, Name: name, Kind: TimerEventKind, TimerDuration: duration})
+	return id
+}
+
+func (this *Builder) EndEvent(id string) string {
+	this.nodes = append(this.nodes, Node{Id: id, Kind: EndEventKind})
+	return id
+}
+
+// Flow connects two nodes by their ids
+func (this *Builder) Flow(source string, target string) {
+	this.flows = append(this.flows, Flow{Id: fmt.Sprintf("SequenceFlow_%v_%v", source, target), Source: source, Target: target})
+}
+
+// ConditionalFlow connects an exclusive gateway to a node, taken if the condition expression is true
+func (this *Builder) ConditionalFlow(source string, target string, condition string) {
+	this.Flow(source, target)
+	this.flows[len(this.flows)-1].Condition = condition
+}
+
+// DefaultFlow connects an exclusive gateway to a node, taken if no condition matches
+func (this *Builder) DefaultFlow(source string, target string) {
+	this.Flow(source, target)
+	for i, node := range this.nodes {
+		if node.Id == source {
+			this.nodes[i].DefaultFlow = this.flows[len(this.flows)-1].Id
+		}
+	}
+}
+
+// Chain connects the given nodes in order
+func (this *Builder) Chain(ids ...string) {
+	for i := 1; i < len(ids); i++ {
+		this.Flow(ids[i-1], ids[i])
+	}
+}
+
+func (this *Builder) Xml() (string, error) {
+	buf := &strings.Builder{}
+	buf.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	buf.WriteString(`<bpmn:definitions xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:bpmn="http://www.omg.org/spec/BPMN/20100524/MODEL" xmlns:bpmndi="http://www.omg.org/spec/BPMN/20100524/DI" xmlns:dc="http://www.omg.org/spec/DD/20100524/DC" xmlns:camunda="http://camunda.org/schema/1.0/bpmn" xmlns:senergy="https://senergy.infai.org" xmlns:di="http://www.omg.org/spec/DD/20100524/DI" id="Definitions_1" targetNamespace="http://bpmn.io/schema/bpmn">`)
+	buf.WriteString(`<bpmn:process id="` + escape(this.processId) + `" isExecutable="true">`)
+	for _, node := range this.nodes {
+		err := this.writeNode(buf, node)
+		if err != nil {
+			return "", err
+		}
+	}
+	for _, flow := range this.flows {
+		buf.WriteString(`<bpmn:sequenceFlow id="` + escape(flow.Id) + `" sourceRef="` + escape(flow.Source) + `" targetRef="` + escape(flow.Target) + `">`)
+		if flow.Condition != "" {
+			buf.WriteString(`<bpmn:conditionExpression xsi:type="bpmn:tFormalExpression">` + escape(flow.Condition) + `</bpmn:conditionExpression>`)
+		}
+		buf.WriteString(`</bpmn:sequenceFlow>`)
+	}
+	buf.WriteString(`</bpmn:process>`)
+	buf.WriteString(this.diagramXml())
+	buf.WriteString(`</bpmn:definitions>`)
+	return buf.String(), nil
+}
+
+func (this *Builder) writeNode(buf *strings.Builder, node Node) error {
+	switch node.Kind {
+	case StartEventKind:
+		buf.WriteString(`<bpmn:startEvent id="` + escape(node.Id) + `">`)
+		if len(node.StartParameters) > 0 {
+			buf.WriteString(`<bpmn:extensionElements><camunda:formData>`)
+			for _, parameter := range node.StartParameters {
+				buf.WriteString(`<camunda:formField id="` + escape(parameter.Id) + `" label="` + escape(parameter.Label) + `" type="string" defaultValue="` + escape(parameter.Default) + `" />`)
+			}
+			buf.WriteString(`</camunda:formData></bpmn:extensionElements>`)
+		}
+		this.writeFlowRefs(buf, node.Id)
+		buf.WriteString(`</bpmn:startEvent>`)
+	case ConditionalStartEventKind:
+		e := node.ConditionalEvent
+		buf.WriteString(fmt.Sprintf(`<bpmn:startEvent id="%v" name="%v" senergy:aspect="%v" senergy:function="%v" senergy:characteristic="%v" senergy:script="%v" senergy:value_variable_name="%v" senergy:qos="%v">`,
+			escape(node.Id), escape(node.Name), escape(e.AspectId), escape(e.FunctionId), escape(e.CharacteristicId), escape(e.Script), escape(e.ValueVariableName), e.Qos))
+		this.writeFlowRefs(buf, node.Id)
+		buf.WriteString(`<bpmn:messageEventDefinition /></bpmn:startEvent>`)
+	case ServiceTaskKind:
+		payload, err := getTaskPayload(node)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(`<bpmn:serviceTask id="` + escape(node.Id) + `" name="` + escape(node.Name) + `" camunda:type="external" camunda:topic="pessimistic">`)
+		buf.WriteString(`<bpmn:extensionElements><camunda:inputOutput>`)
+		buf.WriteString(`<camunda:inputParameter name="payload">` + escape(payload) + `</camunda:inputParameter>`)
+		if !node.Task.Measuring {
+			buf.WriteString(`<camunda:inputParameter name="inputs">0</camunda:inputParameter>`)
+		}
+		for _, name := range sortedKeys(node.Task.Outputs) {
+			buf.WriteString(`<camunda:outputParameter name="` + escape(name) + `">` + escape(node.Task.Outputs[name]) + `</camunda:outputParameter>`)
+		}
+		buf.WriteString(`</camunda:inputOutput></bpmn:extensionElements>`)
+		this.writeFlowRefs(buf, node.Id)
+		buf.WriteString(`</bpmn:serviceTask>`)
+	case ScriptTaskKind:
+		buf.WriteString(`<bpmn:scriptTask id="` + escape(node.Id) + `" name="` + escape(node.Name) + `" scriptFormat="javascript">`)
+		this.writeFlowRefs(buf, node.Id)
+		buf.WriteString(`<bpmn:script>` + escape(node.Script) + `</bpmn:script></bpmn:scriptTask>`)
+	case ExclusiveGatewayKind:
+		buf.WriteString(`<bpmn:exclusiveGateway id="` + escape(node.Id) + `"`)
+		if node.DefaultFlow != "" {
+			buf.WriteString(` default="` + escape(node.DefaultFlow) + `"`)
+		}
+		buf.WriteString(`>`)
+		this.writeFlowRefs(buf, node.Id)
+		buf.WriteString(`</bpmn:exclusiveGateway>`)
+	case ParallelGatewayKind:
+		buf.WriteString(`<bpmn:parallelGateway id="` + escape(node.Id) + `">`)
+		this.writeFlowRefs(buf, node.Id)
+		buf.WriteString(`</bpmn:parallelGateway>`)
+	case TimerEventKind:
+		buf.WriteString(`<bpmn:intermediateCatchEvent id="` + escape(node.Id) + `" name="` + escape(node.Name) + `">`)
+		this.writeFlowRefs(buf, node.Id)
+		buf.WriteString(`<bpmn:timerEventDefinition><bpmn:timeDuration xsi:type="bpmn:tFormalExpression">` + escape(node.TimerDuration) + `</bpmn:timeDuration></bpmn:timerEventDefinition>`)
+		buf.WriteString(`</bpmn:intermediateCatchEvent>`)
+	case EndEventKind:
+		buf.WriteString(`<bpmn:endEvent id="` + escape(node.Id) + `">`)
+		this.writeFlowRefs(buf, node.Id)
+		buf.WriteString(`</bpmn:endEvent>`)
+	default:
+		return fmt.Errorf("unknown node kind %v", node.Kind)
+	}
+	return nil
+}
+
+func (this *Builder) writeFlowRefs(buf *strings.Builder, nodeId string) {
+	for _, flow := range this.flows {
+		if flow.Target == nodeId {
+			buf.WriteString(`<bpmn:incoming>` + escape(flow.Id) + `</bpmn:incoming>`)
+		}
+	}
+	for _, flow := range this.flows {
+		if flow.Source == nodeId {
+			buf.WriteString(`<bpmn:outgoing>` + escape(flow.Id) + `</bpmn:outgoing>`)
+		}
+	}
+}
+
+// getTaskPayload creates the task payload, as expected by the process-deployment service
+func getTaskPayload(node Node) (string, error) {
+	var aspect interface{}
+	if node.Task.Aspect != nil {
+		aspect = node.Task.Aspect
+	} else if node.Task.AspectId != "" {
+		aspect = map[string]string{"id": node.Task.AspectId}
+	}
+	rdfType := ControllingFunctionRdfType
+	if node.Task.Measuring {
+		rdfType = MeasuringFunctionRdfType
+	}
+	var function interface{} = map[string]string{
+		"id":       node.Task.FunctionId,
+		"rdf_type": rdfType,
+	}
+	label := node.Name
+	if node.Task.Function != nil {
+		function = node.Task.Function
+		label = node.Task.Function.Name //the process designer labels tasks by function
+	}
+	var deviceClass interface{}
+	if node.Task.DeviceClass != nil {
+		deviceClass = node.Task.DeviceClass
+	} else if node.Task.DeviceClassId != "" {
+		deviceClass = map[string]string{"id": node.Task.DeviceClassId}
+	}
+	payload, err := json.MarshalIndent(map[string]interface{}{
+		"version":           2,
+		"function":          function,
+		"device_class":      deviceClass,
+		"aspect":            aspect,
+		"label":             label,
+		"input":             0,
+		"characteristic_id": node.Task.CharacteristicId,
+		"retries":           node.Task.Retries,
+		"prefer_event":      node.Task.PreferEvent,
+	}, "", "    ")
+	return string(payload), err
+}
+
+func sortedKeys(m map[string]string) (result []string) {
+	for key := range m {
+		result = append(result, key)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func escape(s string) string {
+	buf := &bytes.Buffer{}
+	_ = xml.EscapeText(buf, []byte(s))
+	return buf.String()
+}
@@ -0,0 +1,209 @@
+/*
+ * Copyright (c) 2023 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bpmn
+
+// Deployment is the v3 deployment model of the process-deployment service
+type Deployment struct {
+	Version          int               `json:"version"`
+	Id               string            `json:"id"`
+	Name             string            `json:"name"`
+	Description      string            `json:"description"`
+	Diagram          Diagram           `json:"diagram"`
+	Elements         []Element         `json:"elements"`
+	Executable       bool              `json:"executable"`
+	IncidentHandling *IncidentHandling `json:"incident_handling,omitempty"`
+	StartParameter   []StartParameter  `json:"start_parameter"`
+}
+
+type Diagram struct {
+	XmlRaw      string `json:"xml_raw"`
+	XmlDeployed string `json:"xml_deployed"`
+	Svg         string `json:"svg"`
+}
+
+type IncidentHandling struct {
+	Restart bool `json:"restart"`
+	Notify  bool `json:"notify"`
+}
+
+type StartParameter struct {
+	Id         string            `json:"id"`
+	Label      string            `json:"label"`
+	Type       string            `json:"type"`
+	Default    string            `json:"default"`
+	Properties map[string]string `json:"properties"`
+}
+
+type Element struct {
+	BpmnId           string            `json:"bpmn_id"`
+	Group            *string           `json:"group"`
+	Name             string            `json:"name"`
+	Order            int64             `json:"order"`
+	TimeEvent        *TimeEvent        `json:"time_event"`
+	Notification     interface{}       `json:"notification"`
+	MessageEvent     interface{}       `json:"message_event"`
+	ConditionalEvent *ConditionalEvent `json:"conditional_event"`
+	Task             *Task             `json:"task"`
+}
+
+type TimeEvent struct {
+	Type string `json:"type"`
+	Time string `json:"time"`
+}
+
+type Task struct {
+	Retries   int64             `json:"retries"`
+	Parameter map[string]string `json:"parameter"`
+	Selection Selection         `json:"selection"`
+}
+
+type ConditionalEvent struct {
+	Script        string            `json:"script"`
+	ValueVariable string            `json:"value_variable"`
+	Variables     map[string]string `json:"variables"`
+	Qos           int               `json:"qos"`
+	EventId       string            `json:"event_id"`
+	Selection     Selection         `json:"selection"`
+}
+
+type Selection struct {
+	FilterCriteria             FilterCriteria `json:"filter_criteria"`
+	SelectionOptions           []interface{}  `json:"selection_options"`
+	SelectedDeviceId           *string        `json:"selected_device_id"`
+	SelectedServiceId          *string        `json:"selected_service_id"`
+	SelectedDeviceGroupId      *string        `json:"selected_device_group_id"`
+	SelectedImportId           *string        `json:"selected_import_id"`
+	SelectedGenericEventSource interface{}    `json:"selected_generic_event_source"`
+	SelectedPath               interface{}    `json:"selected_path"`
+}
+
+type FilterCriteria struct {
+	CharacteristicId *string `json:"characteristic_id"`
+	FunctionId       *string `json:"function_id"`
+	DeviceClassId    *string `json:"device_class_id"`
+	AspectId         *string `json:"aspect_id"`
+}
+
+// SelectDevice lets the element use the given device service
+func (this *Selection) SelectDevice(deviceId string, serviceId string) {
+	this.SelectedDeviceId = &deviceId
+	this.SelectedServiceId = &serviceId
+	this.SelectedDeviceGroupId = nil
+}
+
+// SelectDeviceGroup lets the element use all matching services of the given device group
+func (this *Selection) SelectDeviceGroup(deviceGroupId string) {
+	this.SelectedDeviceId = nil
+	this.SelectedServiceId = nil
+	this.SelectedDeviceGroupId = &deviceGroupId
+}
+
+// Deployment creates a deployment with one element per service task, timer event and conditional start event.
+// the selections of the returned elements are empty and have to be set by the caller.
+func (this *Builder) Deployment(name string) (result Deployment, err error) {
+	xml, err := this.Xml()
+	if err != nil {
+		return result, err
+	}
+	result = Deployment{
+		Version:          3,
+		Name:             name,
+		Diagram:          Diagram{XmlRaw: xml, Svg: this.Svg()},
+		Elements:         []Element{},
+		Executable:       true,
+		IncidentHandling: &IncidentHandling{Restart: false, Notify: true},
+		StartParameter:   []StartParameter{},
+	}
+	for _, node := range this.nodes {
+		switch node.Kind {
+		case StartEventKind:
+			for _, parameter := range node.StartParameters {
+				result.StartParameter = append(result.StartParameter, StartParameter{
+					Id:         parameter.Id,
+					Label:      parameter.Label,
+					Type:       "string",
+					Default:    parameter.Default,
+					Properties: map[string]string{},
+				})
+			}
+		case ServiceTaskKind:
+			result.Elements = append(result.Elements, Element{
+				BpmnId: node.Id,
+				Name:   node.Name,
+				Order:  int64(len(result.Elements)),
+				Task: &Task{
+					Retries:   int64(node.Task.Retries),
+					Parameter: map[string]string{},
+					Selection: Selection{
+						FilterCriteria: FilterCriteria{
+							CharacteristicId: optional(node.Task.CharacteristicId),
+							FunctionId:       optional(node.Task.FunctionId),
+							DeviceClassId:    optional(node.Task.DeviceClassId),
+							AspectId:         optional(node.Task.AspectId),
+						},
+						SelectionOptions: []interface{}{},
+					},
+				},
+			})
+		case TimerEventKind:
+			result.Elements = append(result.Elements, Element{
+				BpmnId:    node.Id,
+				Name:      node.Name,
+				Order:     int64(len(result.Elements)),
+				TimeEvent: &TimeEvent{Type: "timeDuration", Time: node.TimerDuration},
+			})
+		case ConditionalStartEventKind:
+			result.Elements = append(result.Elements, Element{
+				BpmnId: node.Id,
+				Name:   node.Name,
+				Order:  int64(len(result.Elements)),
+				ConditionalEvent: &ConditionalEvent{
+					Script:        node.ConditionalEvent.Script,
+					ValueVariable: node.ConditionalEvent.ValueVariableName,
+					Variables:     map[string]string{},
+					Qos:           node.ConditionalEvent.Qos,
+					Selection: Selection{
+						FilterCriteria: FilterCriteria{
+							CharacteristicId: optional(node.ConditionalEvent.CharacteristicId),
+							FunctionId:       optional(node.ConditionalEvent.FunctionId),
+							AspectId:         optional(node.ConditionalEvent.AspectId),
+						},
+						SelectionOptions: []interface{}{},
+					},
+				},
+			})
+		}
+	}
+	return result, nil
+}
+
+// Element returns the deployment element with the given bpmn id or nil
+func (this *Deployment) Element(bpmnId string) *Element {
+	for i := range this.Elements {
+		if this.Elements[i].BpmnId == bpmnId {
+			return &this.Elements[i]
+		}
+	}
+	return nil
+}
+
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,133 @@
+/*
+ * Copyright (c) 2023 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package bpmn
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBuilderDeployment(t *testing.T) {
+	tests := []struct {
+		name                   string
+		build                  func(builder *Builder)
+		expectedElementIds     []string
+		expectedStartParameter []StartParameter
+		check                  func(t *testing.T, deployment Deployment)
+	}{
+		{
+			name: "only elements for tasks timers and conditional events",
+			build: func(builder *Builder) {
+				builder.Chain(
+					builder.StartEvent("start"),
+					builder.ServiceTask("task1", "Task 1", TaskInfo{FunctionId: "f1", CharacteristicId: "c1", Retries: 2}),
+					builder.ParallelGateway("fork"),
+					builder.ScriptTask("script", "Script", "1+1"),
+					builder.ExclusiveGateway("gateway"),
+					builder.TimerEvent("timer", "Timer", "PT10S"),
+					builder.ServiceTask("task2", "Task 2", TaskInfo{FunctionId: "f2"}),
+					builder.EndEvent("end"),
+				)
+			},
+			expectedElementIds:     []string{"task1", "timer", "task2"},
+			expectedStartParameter: []StartParameter{},
+			check: func(t *testing.T, deployment Deployment) {
+				task := deployment.Element("task1")
+				if task.Task == nil || task.Task.Retries != 2 || task.Name != "Task 1" {
+					t.Errorf("unexpected task element %#v", task)
+				}
+				criteria := task.Task.Selection.FilterCriteria
+				if criteria.FunctionId == nil || *criteria.FunctionId != "f1" || criteria.CharacteristicId == nil || *criteria.CharacteristicId != "c1" {
+					t.Errorf("unexpected filter criteria %#v", criteria)
+				}
+				if criteria.DeviceClassId != nil || criteria.AspectId != nil {
+					t.Errorf("empty ids should be nil: %#v", criteria)
+				}
+				timer := deployment.Element("timer")
+				if timer.TimeEvent == nil || timer.TimeEvent.Time != "PT10S" || timer.TimeEvent.Type != "timeDuration" {
+					t.Errorf("unexpected timer element %#v", timer)
+				}
+				if deployment.Element("script") != nil || deployment.Element("start") != nil {
+					t.Error("unexpected element for script task or start event")
+				}
+			},
+		},
+		{
+			name: "start parameters",
+			build: func(builder *Builder) {
+				builder.Chain(
+					builder.StartEvent("start", StartParameterInfo{Id: "p1", Label: "P1", Default: "d1"}, StartParameterInfo{Id: "p2", Label: "P2"}),
+					builder.EndEvent("end"),
+				)
+			},
+			expectedElementIds: []string{},
+			expectedStartParameter: []StartParameter{
+				{Id: "p1", Label: "P1", Type: "string", Default: "d1", Properties: map[string]string{}},
+				{Id: "p2", Label: "P2", Type: "string", Default: "", Properties: map[string]string{}},
+			},
+		},
+		{
+			name: "conditional start event",
+			build: func(builder *Builder) {
+				builder.Chain(
+					builder.ConditionalStartEvent("start", "Start", ConditionalEventInfo{FunctionId: "f", CharacteristicId: "c", Script: "value > 1", ValueVariableName: "value", Qos: 1}),
+					builder.EndEvent("end"),
+				)
+			},
+			expectedElementIds:     []string{"start"},
+			expectedStartParameter: []StartParameter{},
+			check: func(t *testing.T, deployment Deployment) {
+				event := deployment.Element("start").ConditionalEvent
+				if event == nil || event.Script != "value > 1" || event.ValueVariable != "value" || event.Qos != 1 {
+					t.Fatalf("unexpected conditional event %#v", event)
+				}
+				criteria := event.Selection.FilterCriteria
+				if criteria.FunctionId == nil || *criteria.FunctionId != "f" || criteria.AspectId != nil || criteria.DeviceClassId != nil {
+					t.Errorf("unexpected filter criteria %#v", criteria)
+				}
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			builder := NewBuilder("process")
+			test.build(builder)
+			deployment, err := builder.Deployment("name")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if deployment.Name != "name" || deployment.Version != 3 || !deployment.Executable || deployment.Diagram.XmlRaw == "" {
+				t.Errorf("unexpected deployment %#v", deployment)
+			}
+			ids := []string{}
+			for i, element := range deployment.Elements {
+				ids = append(ids, element.BpmnId)
+				if element.Order != int64(i) {
+					t.Errorf("element %v has order %v; expected %v", element.BpmnId, element.Order, i)
+				}
+			}
+			if !reflect.DeepEqual(ids, test.expectedElementIds) {
+				t.Errorf("elements=%#v; expected %#v", ids, test.expectedElementIds)
+			}
+			if !reflect.DeepEqual(deployment.StartParameter, test.expectedStartParameter) {
+				t.Errorf("start parameter=%#v; expected %#v", deployment.StartParameter, test.expectedStartParameter)
+			}
+			if test.check != nil {
+				test.check(t, deployment)
+			}
+		})
+	}
+}
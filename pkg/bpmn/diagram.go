@@ -0,0 +1,136 @@
+/*
+ * Copyright (c) 2023 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bpmn
+
+import (
+	"fmt"
+	"strings"
+)
+
+const columnWidth = 150
+const rowHeight = 120
+const marginX = 50
+const marginY = 50
+
+type bounds struct {
+	X, Y, Width, Height int
+}
+
+func (this bounds) centerY() int {
+	return this.Y + this.Height/2
+}
+
+func nodeSize(kind NodeKind) (width int, height int) {
+	switch kind {
+	case ServiceTaskKind, ScriptTaskKind:
+		return 100, 80
+	case ExclusiveGatewayKind, ParallelGatewayKind:
+		return 50, 50
+	default:
+		return 36, 36
+	}
+}
+
+// layout places nodes in columns by their distance from the start nodes, and in rows by their order within a column
+func (this *Builder) layout() map[string]bounds {
+	column := map[string]int{}
+	for _, node := range this.nodes {
+		column[node.Id] = 0
+	}
+	//longest path; bounded by node count to tolerate cycles
+	for i := 0; i < len(this.nodes); i++ {
+		for _, flow := range this.flows {
+			if column[flow.Target] < column[flow.Source]+1 {
+				column[flow.Target] = column[flow.Source] + 1
+			}
+		}
+	}
+	rows := map[int]int{}
+	result := map[string]bounds{}
+	for _, node := range this.nodes {
+		c := column[node.Id]
+		r := rows[c]
+		rows[c] = r + 1
+		width, height := nodeSize(node.Kind)
+		centerX := marginX + c*columnWidth + 50
+		centerY := marginY + r*rowHeight + 40
+		result[node.Id] = bounds{X: centerX - width/2, Y: centerY - height/2, Width: width, Height: height}
+	}
+	return result
+}
+
+func (this *Builder) diagramXml() string {
+	positions := this.layout()
+	buf := &strings.Builder{}
+	buf.WriteString(`<bpmndi:BPMNDiagram id="BPMNDiagram_1"><bpmndi:BPMNPlane id="BPMNPlane_1" bpmnElement="` + escape(this.processId) + `">`)
+	for _, flow := range this.flows {
+		source, target := positions[flow.Source], positions[flow.Target]
+		buf.WriteString(fmt.Sprintf(`<bpmndi:BPMNEdge id="%v_di" bpmnElement="%v"><di:waypoint x="%v" y="%v" /><di:waypoint x="%v" y="%v" /></bpmndi:BPMNEdge>`,
+			escape(flow.Id), escape(flow.Id), source.X+source.Width, source.centerY(), target.X, target.centerY()))
+	}
+	for _, node := range this.nodes {
+		b := positions[node.Id]
+		buf.WriteString(fmt.Sprintf(`<bpmndi:BPMNShape id="%v_di" bpmnElement="%v"><dc:Bounds x="%v" y="%v" width="%v" height="%v" /></bpmndi:BPMNShape>`,
+			escape(node.Id), escape(node.Id), b.X, b.Y, b.Width, b.Height))
+	}
+	buf.WriteString(`</bpmndi:BPMNPlane></bpmndi:BPMNDiagram>`)
+	return buf.String()
+}
+
+// Svg renders a simplified image of the process, used as deployment preview
+func (this *Builder) Svg() string {
+	positions := this.layout()
+	width, height := 0, 0
+	for _, b := range positions {
+		width = max(width, b.X+b.Width+marginX)
+		height = max(height, b.Y+b.Height+marginY)
+	}
+	buf := &strings.Builder{}
+	buf.WriteString(fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%v" height="%v" viewBox="0 0 %v %v">`, width, height, width, height))
+	for _, flow := range this.flows {
+		source, target := positions[flow.Source], positions[flow.Target]
+		buf.WriteString(fmt.Sprintf(`<polyline data-element-id="%v" points="%v,%v %v,%v" style="fill: none; stroke: black; stroke-width: 2px;" />`,
+			escape(flow.Id), source.X+source.Width, source.centerY(), target.X, target.centerY()))
+	}
+	for _, node := range this.nodes {
+		b := positions[node.Id]
+		buf.WriteString(`<g data-element-id="` + escape(node.Id) + `">`)
+		switch node.Kind {
+		case ServiceTaskKind, ScriptTaskKind:
+			buf.WriteString(fmt.Sprintf(`<rect x="%v" y="%v" width="%v" height="%v" rx="10" ry="10" style="stroke: black; stroke-width: 2px; fill: white;" />`, b.X, b.Y, b.Width, b.Height))
+			buf.WriteString(fmt.Sprintf(`<text x="%v" y="%v" text-anchor="middle" style="font-family: Arial, sans-serif; font-size: 12px;">%v</text>`, b.X+b.Width/2, b.centerY(), escape(node.Name)))
+		case ExclusiveGatewayKind, ParallelGatewayKind:
+			centerX := b.X + b.Width/2
+			buf.WriteString(fmt.Sprintf(`<polygon points="%v,%v %v,%v %v,%v %v,%v" style="stroke: black; stroke-width: 2px; fill: white;" />`,
+				centerX, b.Y, b.X+b.Width, b.centerY(), centerX, b.Y+b.Height, b.X, b.centerY()))
+			symbol := "X"
+			if node.Kind == ParallelGatewayKind {
+				symbol = "+"
+			}
+			buf.WriteString(fmt.Sprintf(`<text x="%v" y="%v" text-anchor="middle" style="font-family: Arial, sans-serif; font-size: 20px;">%v</text>`, centerX, b.centerY()+7, symbol))
+		default:
+			strokeWidth := 2
+			if node.Kind == EndEventKind {
+				strokeWidth = 4
+			}
+			buf.WriteString(fmt.Sprintf(`<circle cx="%v" cy="%v" r="%v" style="stroke: black; stroke-width: %vpx; fill: white;" />`, b.X+b.Width/2, b.centerY(), b.Width/2, strokeWidth))
+		}
+		buf.WriteString(`</g>`)
+	}
+	buf.WriteString(`</svg>`)
+	return buf.String()
+}
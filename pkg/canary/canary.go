@@ -18,56 +18,161 @@ package canary
 
 import (
 	"context"
+	"fmt"
 	devicerepo "github.com/SENERGY-Platform/device-repository/lib/client"
 	"github.com/SENERGY-Platform/snowflake-canary/pkg/configuration"
 	"github.com/SENERGY-Platform/snowflake-canary/pkg/devicemetadata"
 	"github.com/SENERGY-Platform/snowflake-canary/pkg/events"
 	"github.com/SENERGY-Platform/snowflake-canary/pkg/metrics"
 	"github.com/SENERGY-Platform/snowflake-canary/pkg/process"
+	"github.com/SENERGY-Platform/snowflake-canary/pkg/processplatform"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"log"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type Canary struct {
-	metrics              *metrics.Metrics
-	reg                  *prometheus.Registry
-	config               configuration.Config
-	promHttpHandler      http.Handler
-	isRunningMux         sync.Mutex
-	isRunning            bool
-	guaranteeChangeAfter time.Duration
-	devicerepo           devicerepo.Interface
-	process              Process
-	events               Event
-	devicemeta           *devicemetadata.DeviceMetaData
+	metrics                            *metrics.Metrics
+	reg                                *prometheus.Registry
+	config                             configuration.Config
+	promHttpHandler                    http.Handler
+	isRunningMux                       sync.Mutex
+	isRunning                          bool
+	guaranteeChangeAfter               time.Duration
+	ingestionLatencyTimeout            time.Duration
+	ingestionLatencyPollInterval       time.Duration
+	deviceTypeUpdatePropagationTimeout time.Duration
+	deviceTypeUpdatePollInterval       time.Duration
+	lastValueTimeTolerance             time.Duration
+	sampleTimes                        sampleTimes
+	multiTaskNegativeValue             atomic.Bool //sign of the last sensor value published for the multi task process
+	devicerepo                         devicerepo.Interface
+	process                            Process
+	events                             Event
+	devicemeta                         *devicemetadata.DeviceMetaData
+	janitor                            *devicemetadata.Janitor
+	ontologyCheckInterval              time.Duration
+	ontologyCheckMux                   sync.Mutex
+	lastOntologyCheck                  time.Time
+	ontologyCheckErr                   error
+}
+
+// defaults of optional duration configs, used if the key is missing or empty; they match config.json
+const (
+	defaultIngestionLatencyTimeout            = 30 * time.Second
+	defaultIngestionLatencyPollInterval       = 200 * time.Millisecond
+	defaultLastValueTimeTolerance             = 2 * time.Second
+	defaultDeviceTypeUpdatePropagationTimeout = 5 * time.Minute
+	defaultDeviceTypeUpdatePollInterval       = time.Second
+	defaultProcessInstanceTimeout             = 30 * time.Second
+	defaultProcessInstancePollInterval        = time.Second
+	defaultTimerProcessDuration               = 10 * time.Second
+	defaultTimerProcessTolerance              = 30 * time.Second
+	defaultEventNegativeWindow                = 10 * time.Second
+	defaultJanitorMaxAge                      = time.Hour
+	defaultOntologyCheckInterval              = time.Hour
+)
+
+// parseDuration falls back to defaultValue if value is empty; set but invalid values are an error
+func parseDuration(key string, value string, defaultValue time.Duration) (time.Duration, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+	result, err := time.ParseDuration(value)
+	if err != nil {
+		return result, fmt.Errorf("invalid %v: %w", key, err)
+	}
+	return result, nil
 }
 
 func New(ctx context.Context, wg *sync.WaitGroup, config configuration.Config) (canary *Canary, err error) {
 	guaranteeChangeAfter, err := time.ParseDuration(config.GuaranteeChangeAfter)
+	if err != nil {
+		return canary, err
+	}
+	ingestionLatencyTimeout, err := parseDuration("ingestion_latency_timeout", config.IngestionLatencyTimeout, defaultIngestionLatencyTimeout)
+	if err != nil {
+		return canary, err
+	}
+	ingestionLatencyPollInterval, err := parseDuration("ingestion_latency_poll_interval", config.IngestionLatencyPollInterval, defaultIngestionLatencyPollInterval)
+	if err != nil {
+		return canary, err
+	}
+	deviceTypeUpdatePropagationTimeout, err := parseDuration("device_type_update_propagation_timeout", config.DeviceTypeUpdatePropagationTimeout, defaultDeviceTypeUpdatePropagationTimeout)
+	if err != nil {
+		return canary, err
+	}
+	deviceTypeUpdatePollInterval, err := parseDuration("device_type_update_poll_interval", config.DeviceTypeUpdatePollInterval, defaultDeviceTypeUpdatePollInterval)
+	if err != nil {
+		return canary, err
+	}
+	lastValueTimeTolerance, err := parseDuration("last_value_time_tolerance", config.LastValueTimeTolerance, defaultLastValueTimeTolerance)
+	if err != nil {
+		return canary, err
+	}
+	janitorMaxAge, err := parseDuration("janitor_max_age", config.JanitorMaxAge, defaultJanitorMaxAge)
+	if err != nil {
+		return canary, err
+	}
+	ontologyCheckInterval, err := parseDuration("ontology_check_interval", config.OntologyCheckInterval, defaultOntologyCheckInterval)
+	if err != nil {
+		return canary, err
+	}
+	processInstanceTimeout, err := parseDuration("process_instance_timeout", config.ProcessInstanceTimeout, defaultProcessInstanceTimeout)
+	if err != nil {
+		return canary, err
+	}
+	processInstancePollInterval, err := parseDuration("process_instance_poll_interval", config.ProcessInstancePollInterval, defaultProcessInstancePollInterval)
+	if err != nil {
+		return canary, err
+	}
+	timerProcessDuration, err := parseDuration("timer_process_duration", config.TimerProcessDuration, defaultTimerProcessDuration)
+	if err != nil {
+		return canary, err
+	}
+	timerProcessTolerance, err := parseDuration("timer_process_tolerance", config.TimerProcessTolerance, defaultTimerProcessTolerance)
+	if err != nil {
+		return canary, err
+	}
+	eventNegativeWindow, err := parseDuration("event_negative_window", config.EventNegativeWindow, defaultEventNegativeWindow)
+	if err != nil {
+		return canary, err
+	}
 	reg := prometheus.NewRegistry()
 
 	m := metrics.NewMetrics(reg)
 
 	d := devicerepo.NewClient(config.DeviceRepositoryUrl, nil)
-	devicemeta := devicemetadata.NewDeviceMetaData(d, m, config, guaranteeChangeAfter)
+	devicemeta := devicemetadata.NewDeviceMetaData(d, m, config, guaranteeChangeAfter, deviceTypeUpdatePropagationTimeout, deviceTypeUpdatePollInterval)
+
+	platform := processplatform.New(config, m, processInstanceTimeout, processInstancePollInterval)
 
-	p := process.New(config, d, m, guaranteeChangeAfter)
+	janitor := devicemetadata.NewJanitor(d, platform, m, config, janitorMaxAge)
 
-	e := events.New(config, d, m, guaranteeChangeAfter)
+	p := process.New(config, d, m, platform, guaranteeChangeAfter, timerProcessDuration, timerProcessTolerance)
+
+	e := events.New(config, d, m, platform, guaranteeChangeAfter, eventNegativeWindow)
 
 	return &Canary{
-		reg:                  reg,
-		metrics:              m,
-		config:               config,
-		devicerepo:           d,
-		guaranteeChangeAfter: guaranteeChangeAfter,
-		devicemeta:           devicemeta,
-		process:              p,
-		events:               e,
+		reg:                                reg,
+		metrics:                            m,
+		config:                             config,
+		devicerepo:                         d,
+		guaranteeChangeAfter:               guaranteeChangeAfter,
+		ingestionLatencyTimeout:            ingestionLatencyTimeout,
+		ingestionLatencyPollInterval:       ingestionLatencyPollInterval,
+		deviceTypeUpdatePropagationTimeout: deviceTypeUpdatePropagationTimeout,
+		deviceTypeUpdatePollInterval:       deviceTypeUpdatePollInterval,
+		lastValueTimeTolerance:             lastValueTimeTolerance,
+		devicemeta:                         devicemeta,
+		janitor:                            janitor,
+		ontologyCheckInterval:              ontologyCheckInterval,
+		process:                            p,
+		events:                             e,
 	}, nil
 }
 
@@ -75,11 +180,24 @@ type Process interface {
 	NotifyCommand(topic string, payload []byte) error
 	ProcessStartup(token string, info DeviceInfo) error
 	ProcessTeardown(token string) error
+	GroupProcessStartup(token string, groupId string) error
+	GroupProcessTeardown(token string) error
+	ConversionProcessStartup(token string, info DeviceInfo) error
+	ConversionProcessTeardown(token string) error
+	IncidentProcessStartup(token string, info DeviceInfo) error
+	IncidentProcessTeardown(token string) error
+	MultiTaskProcessStartup(token string, info DeviceInfo, sensorValue float64) error
+	MultiTaskProcessTeardown(token string) error
+	TimerProcessStartup(token string, info DeviceInfo) error
+	TimerProcessTeardown(token string) error
+	StartParameterProcessStartup(token string, info DeviceInfo) error
+	StartParameterProcessTeardown(token string) error
 }
 
 type Event interface {
 	ProcessStartup(token string, info DeviceInfo) error
-	ProcessTeardown(token string) error
+	ProcessTeardown(token string, publishedValue int, publishedAt time.Time) error
+	CheckNoInstanceStarted(token string, value int, publishedAt time.Time)
 }
 
 func (this *Canary) GetMetricsHandler() (h http.Handler, err error) {
@@ -19,11 +19,13 @@ package canary
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/SENERGY-Platform/device-repository/lib/model"
 	"github.com/SENERGY-Platform/models/go/models"
 	"github.com/SENERGY-Platform/snowflake-canary/pkg/configuration"
 	"github.com/SENERGY-Platform/snowflake-canary/pkg/devicemetadata"
+	"github.com/SENERGY-Platform/snowflake-canary/pkg/process"
 	paho "github.com/eclipse/paho.mqtt.golang"
 	"log"
 	"net/http"
@@ -31,6 +33,7 @@ import (
 	"runtime/debug"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -102,6 +105,10 @@ func (this *Canary) subscribe(info DeviceInfo, conn *Conn) {
 	start := time.Now()
 	token := conn.Client.Subscribe(topic, 2, func(c paho.Client, message paho.Message) {
 		err := this.process.NotifyCommand(message.Topic(), message.Payload())
+		if errors.Is(err, process.ErrIncidentCommand) {
+			go this.respondError(conn, message.Payload())
+			return
+		}
 		if err != nil {
 			log.Println("ERROR: unexpected command error", err)
 			this.metrics.UncategorizedErr.Inc()
@@ -165,32 +172,58 @@ func (this *Canary) respond(conn *Conn, cmdtopic string, cmdpayload []byte) {
 	}
 }
 
-func (this *Canary) publish(info DeviceInfo, conn *Conn, value1 int, value2 int) {
-	msg, err := getMessage(this.config, value1, value2)
+// respondError reports the command as failed, which lets the process task fail
+func (this *Canary) respondError(conn *Conn, cmdpayload []byte) {
+	request := RequestEnvelope{}
+	err := json.Unmarshal(cmdpayload, &request)
 	if err != nil {
+		log.Println("ERROR: unable to decode request envalope", err)
+		return
+	}
+	token := conn.Client.Publish("error/command/"+request.CorrelationId, 2, false, "snowflake canary incident check")
+	token.Wait()
+	if token.Error() != nil {
+		log.Println("ERROR: respondError Publish", token.Error())
 		this.metrics.UncategorizedErr.Inc()
 		return
 	}
+}
+
+func (this *Canary) publish(info DeviceInfo, conn *Conn, value1 int, value2 int) (publishedAt time.Time, err error) {
+	return this.publishWithTime(info, conn, value1, value2, time.Now())
+}
 
+// publishWithTime publishes the values with an explicit value time
+func (this *Canary) publishWithTime(info DeviceInfo, conn *Conn, value1 int, value2 int, valueTime time.Time) (publishedAt time.Time, err error) {
+	msg, err := getMessage(this.config, value1, value2, valueTime)
+	if err != nil {
+		this.metrics.UncategorizedErr.Inc()
+		return publishedAt, err
+	}
+	return this.publishMessage(info, conn, msg)
+}
+
+func (this *Canary) publishMessage(info DeviceInfo, conn *Conn, msg []byte) (publishedAt time.Time, err error) {
 	this.metrics.ConnectorPublishCount.Inc()
 	topic := "event/" + info.LocalId + "/sensor"
 	if this.config.TopicsWithOwner {
 		topic = "event/" + info.OwnerId + "/" + info.LocalId + "/sensor"
 	}
 
-	start := time.Now()
+	publishedAt = time.Now() //taken before the publish, to include the mqtt transfer in latencies measured from publishedAt
 	token := conn.Client.Publish(topic, 2, false, msg)
 	token.Wait()
-	this.metrics.ConnectorPublishLatencyMs.Set(float64(time.Since(start).Milliseconds()))
+	this.metrics.ConnectorPublishLatencyMs.Set(float64(time.Since(publishedAt).Milliseconds()))
 	if token.Error() != nil {
 		log.Println("Error on Client.Subscribe(): ", token.Error())
 		this.metrics.ConnectorPublishErr.Inc()
-		return
+		return publishedAt, token.Error()
 	}
+	return publishedAt, nil
 }
 
-func getMessage(config configuration.Config, value1 int, value2 int) (payload []byte, err error) {
-	xmlMsg := fmt.Sprintf(`<measurements><measurement value="%v" /></measurements>`, value1)
+func getMessage(config configuration.Config, value1 int, value2 int, valueTime time.Time) (payload []byte, err error) {
+	xmlMsg := fmt.Sprintf(`<measurements><measurement value="%v" time="%v" /></measurements>`, value1, valueTime.UTC().Format(time.RFC3339Nano))
 	payload, err = json.Marshal(map[string]string{config.CanaryProtocolSegmentName2: strconv.Itoa(value2), config.CanaryProtocolSegmentName: xmlMsg})
 	return
 }
@@ -200,62 +233,26 @@ type LastValue struct {
 	Value interface{} `json:"value"`
 }
 
-func (this *Canary) checkDeviceValue(token string, info DeviceInfo, value1 int, value2 int) {
-	this.metrics.DeviceRepoRequestCount.Inc()
-	start := time.Now()
-	dt, err, _ := this.devicerepo.ReadDeviceType(info.DeviceTypeId, token)
-	this.metrics.DeviceRepoRequestLatencyMs.Set(float64(time.Since(start).Milliseconds()))
+func (this *Canary) checkDeviceValue(token string, info DeviceInfo, value1 int, value2 int, publishedAt time.Time) {
+	service, err := this.getSensorService(token, info)
 	if err != nil {
-		this.metrics.DeviceRepoRequestErr.Inc()
-		log.Println("ERROR:", err)
-		debug.PrintStack()
 		return
 	}
+	serviceId := service.Id
 
-	serviceId := ""
-	for _, s := range dt.Services {
-		if s.LocalId == devicemetadata.SensorServiceLocalId {
-			serviceId = s.Id
-			break
-		}
-	}
-
-	buf := &bytes.Buffer{}
-	err = json.NewEncoder(buf).Encode([]map[string]interface{}{
-		{
-			"deviceId":   info.Id,
-			"serviceId":  serviceId,
-			"columnName": "measurements.measurement.value",
-		},
-		{
-			"deviceId":   info.Id,
-			"serviceId":  serviceId,
-			"columnName": "area",
-		},
-	})
-	if err != nil {
-		return
-	}
 	this.metrics.DeviceDataRequestCount.Inc()
-	req, err := http.NewRequest(http.MethodPost, this.config.LastValueQueryUrl, buf)
-	if err != nil {
-		this.metrics.UncategorizedErr.Inc()
-		log.Println("ERROR:", err)
-		debug.PrintStack()
-		return
-	}
-	req.Header.Set("Authorization", token)
-	start = time.Now()
-	lastValues, _, err := devicemetadata.Do[[]LastValue](req)
-	this.metrics.DeviceDataRequestLatencyMs.Set(float64(time.Since(start).Milliseconds()))
+	start := time.Now()
+	lastValues, err := this.queryLastValues(token, info, serviceId)
+	queriedAt := time.Now()
+	this.metrics.DeviceDataRequestLatencyMs.Set(float64(queriedAt.Sub(start).Milliseconds()))
 	if err != nil {
 		this.metrics.DeviceDataRequestErr.Inc()
 		log.Println("ERROR:", err)
 		debug.PrintStack()
 	}
 
-	expectedValue1 := jsonNormalize(value1)
-	expectedValue2 := jsonNormalize(value2)
+	expectedValue1 := devicemetadata.JsonNormalize(value1)
+	expectedValue2 := devicemetadata.JsonNormalize(value2)
 
 	if len(lastValues) != 2 {
 		this.metrics.UnexpectedDeviceDataErr.Inc()
@@ -271,10 +268,176 @@ func (this *Canary) checkDeviceValue(token string, info DeviceInfo, value1 int,
 		this.metrics.UnexpectedDeviceDataErr.Inc()
 		log.Printf("UnexpectedDeviceDataErr: lastValues[1].Value=%#v, expectedValue2=%#v\n", lastValues[1].Value, expectedValue2)
 	}
+	if !devicemetadata.HasSensorTimePath(service) {
+		log.Println("WARNING: canary device type has no sensor time path; value times are ingestion times and are not checked")
+	} else if !publishedAt.IsZero() {
+		for _, lastValue := range lastValues {
+			this.checkDeviceValueTime(lastValue, publishedAt, queriedAt)
+		}
+	}
+	if reflect.DeepEqual(lastValues[0].Value, expectedValue1) {
+		this.checkSampleOrder(lastValues[0])
+	}
+
+	this.checkDeviceValueConversions(token, info, serviceId, value1)
 }
 
-func jsonNormalize(in interface{}) (out interface{}) {
-	temp, _ := json.Marshal(in)
-	json.Unmarshal(temp, &out)
-	return
+// checkDeviceValueTime ensures that the value time lies between publish and query
+func (this *Canary) checkDeviceValueTime(lastValue LastValue, publishedAt time.Time, queriedAt time.Time) {
+	valueTime, err := time.Parse(time.RFC3339Nano, lastValue.Time)
+	if err != nil {
+		this.metrics.UnexpectedDeviceDataTimeErr.Inc()
+		log.Printf("UnexpectedDeviceDataTimeErr: unable to parse last value time %#v: %v\n", lastValue.Time, err)
+		return
+	}
+	this.metrics.DeviceDataClockSkewMs.Set(float64(valueTime.Sub(publishedAt).Milliseconds()))
+	if valueTime.Before(publishedAt.Add(-this.lastValueTimeTolerance)) || valueTime.After(queriedAt.Add(this.lastValueTimeTolerance)) {
+		this.metrics.UnexpectedDeviceDataTimeErr.Inc()
+		log.Printf("UnexpectedDeviceDataTimeErr: value time %v not between publish %v and query %v (tolerance %v)\n", valueTime, publishedAt, queriedAt, this.lastValueTimeTolerance)
+	}
+}
+
+// sampleTimes remembers the value time of the canary sample of the current test run, as seen by the last-value service
+type sampleTimes struct {
+	mux  sync.Mutex
+	last time.Time
+}
+
+func (this *sampleTimes) reset() {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.last = time.Time{}
+}
+
+// observe returns the previously seen time and true if valueTime is before it
+func (this *sampleTimes) observe(valueTime time.Time) (previous time.Time, reordered bool) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	if valueTime.Before(this.last) {
+		return this.last, true
+	}
+	this.last = valueTime
+	return this.last, false
+}
+
+// checkSampleOrder is called with the last value of the canary sample of the current run;
+// a value time before the previously seen value time of the same sample indicates reordering in the ingestion pipeline
+func (this *Canary) checkSampleOrder(lastValue LastValue) {
+	valueTime, err := time.Parse(time.RFC3339Nano, lastValue.Time)
+	if err != nil {
+		return //reported by checkDeviceValueTime
+	}
+	previous, reordered := this.sampleTimes.observe(valueTime)
+	if reordered {
+		this.metrics.DeviceDataReorderErr.Inc()
+		log.Printf("DeviceDataReorderErr: value time %v is before previously seen value time %v\n", valueTime, previous)
+	}
+}
+
+func (this *Canary) getSensorServiceId(token string, info DeviceInfo) (serviceId string, err error) {
+	service, err := this.getSensorService(token, info)
+	return service.Id, err
+}
+
+func (this *Canary) getSensorService(token string, info DeviceInfo) (service models.Service, err error) {
+	this.metrics.DeviceRepoRequestCount.Inc()
+	start := time.Now()
+	dt, err, _ := this.devicerepo.ReadDeviceType(info.DeviceTypeId, token)
+	this.metrics.DeviceRepoRequestLatencyMs.Set(float64(time.Since(start).Milliseconds()))
+	if err != nil {
+		this.metrics.DeviceRepoRequestErr.Inc()
+		log.Println("ERROR:", err)
+		debug.PrintStack()
+		return service, err
+	}
+	for _, s := range dt.Services {
+		if s.LocalId == devicemetadata.SensorServiceLocalId {
+			return s, nil
+		}
+	}
+	return service, nil
+}
+
+// queryLastValues requests the last values of the sensor service (measurements.measurement.value and area)
+func (this *Canary) queryLastValues(token string, info DeviceInfo, serviceId string) (lastValues []LastValue, err error) {
+	return this.queryLastValueColumns(token, info.Id, serviceId, "measurements.measurement.value", "area")
+}
+
+type LastValueQuery struct {
+	DeviceId               string `json:"deviceId"`
+	ServiceId              string `json:"serviceId"`
+	ColumnName             string `json:"columnName"`
+	SourceCharacteristicId string `json:"sourceCharacteristicId,omitempty"`
+	TargetCharacteristicId string `json:"targetCharacteristicId,omitempty"`
+}
+
+func (this *Canary) queryLastValueColumns(token string, deviceId string, serviceId string, columns ...string) (lastValues []LastValue, err error) {
+	query := []LastValueQuery{}
+	for _, column := range columns {
+		query = append(query, LastValueQuery{
+			DeviceId:   deviceId,
+			ServiceId:  serviceId,
+			ColumnName: column,
+		})
+	}
+	return this.queryLastValueElements(token, query)
+}
+
+func (this *Canary) queryLastValueElements(token string, query []LastValueQuery) (lastValues []LastValue, err error) {
+	buf := &bytes.Buffer{}
+	err = json.NewEncoder(buf).Encode(query)
+	if err != nil {
+		return lastValues, err
+	}
+	req, err := http.NewRequest(http.MethodPost, this.config.LastValueQueryUrl, buf)
+	if err != nil {
+		return lastValues, err
+	}
+	req.Header.Set("Authorization", token)
+	lastValues, _, err = devicemetadata.Do[[]LastValue](req)
+	return lastValues, err
+}
+
+// measureIngestionLatency polls the last-value service until it returns the published values
+// and exports the time between the publish and the first matching response
+func (this *Canary) measureIngestionLatency(token string, info DeviceInfo, publishedAt time.Time, value1 int, value2 int) {
+	serviceId, err := this.getSensorServiceId(token, info)
+	if err != nil {
+		return
+	}
+	lastValues, err := this.waitForLastValues(token, info, serviceId, value1, value2)
+	if err != nil {
+		this.metrics.DeviceDataIngestionTimeoutErr.Inc()
+		log.Println("ERROR: DeviceDataIngestionTimeoutErr:", err)
+		return
+	}
+	this.metrics.DeviceDataIngestionLatencyMs.Observe(float64(time.Since(publishedAt).Milliseconds()))
+	this.checkSampleOrder(lastValues[0])
+}
+
+// waitForLastValues polls the last values of the sensor service, until they match the published values or the ingestion latency timeout is reached
+func (this *Canary) waitForLastValues(token string, info DeviceInfo, serviceId string, value1 int, value2 int) (lastValues []LastValue, err error) {
+	expectedValue1 := devicemetadata.JsonNormalize(value1)
+	expectedValue2 := devicemetadata.JsonNormalize(value2)
+	timeout := time.After(this.ingestionLatencyTimeout)
+	ticker := time.NewTicker(this.ingestionLatencyPollInterval)
+	defer ticker.Stop()
+	var lastErr error
+	for {
+		this.metrics.DeviceDataRequestCount.Inc()
+		start := time.Now()
+		lastValues, err = this.queryLastValues(token, info, serviceId)
+		this.metrics.DeviceDataRequestLatencyMs.Set(float64(time.Since(start).Milliseconds()))
+		if err != nil {
+			this.metrics.DeviceDataRequestErr.Inc()
+			lastErr = err
+		} else if len(lastValues) == 2 && reflect.DeepEqual(lastValues[0].Value, expectedValue1) && reflect.DeepEqual(lastValues[1].Value, expectedValue2) {
+			return lastValues, nil
+		}
+		select {
+		case <-timeout:
+			return nil, fmt.Errorf("published values not queryable after %v; last error: %v", this.ingestionLatencyTimeout, lastErr)
+		case <-ticker.C:
+		}
+	}
 }
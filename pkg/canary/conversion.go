@@ -0,0 +1,66 @@
+/*
+ * Copyright (c) 2023 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package canary
+
+import (
+	"github.com/SENERGY-Platform/snowflake-canary/pkg/devicemetadata"
+	"log"
+	"math"
+	"runtime/debug"
+	"time"
+)
+
+// checkDeviceValueConversions queries the sensor value converted by the last-value service to other characteristics
+func (this *Canary) checkDeviceValueConversions(token string, info DeviceInfo, serviceId string, value int) {
+	conversions := devicemetadata.GetSensorConversions(this.config)
+	if len(conversions) == 0 {
+		return
+	}
+	query := []LastValueQuery{}
+	for _, conversion := range conversions {
+		query = append(query, LastValueQuery{
+			DeviceId:               info.Id,
+			ServiceId:              serviceId,
+			ColumnName:             "measurements.measurement.value",
+			SourceCharacteristicId: this.config.CanarySensorCharacteristicId,
+			TargetCharacteristicId: conversion.CharacteristicId,
+		})
+	}
+	this.metrics.DeviceDataRequestCount.Inc()
+	start := time.Now()
+	lastValues, err := this.queryLastValueElements(token, query)
+	this.metrics.DeviceDataRequestLatencyMs.Set(float64(time.Since(start).Milliseconds()))
+	if err != nil {
+		this.metrics.DeviceDataRequestErr.Inc()
+		log.Println("ERROR:", err)
+		debug.PrintStack()
+		return
+	}
+	if len(lastValues) != len(conversions) {
+		this.metrics.UnexpectedDeviceDataConversionErr.Inc()
+		log.Printf("UnexpectedDeviceDataConversionErr: lastValues=%#v\n", lastValues)
+		return
+	}
+	for i, conversion := range conversions {
+		expected := conversion.Convert(float64(value))
+		actual, ok := lastValues[i].Value.(float64)
+		if !ok || math.Abs(actual-expected) > math.Abs(expected)*conversion.Tolerance {
+			this.metrics.UnexpectedDeviceDataConversionErr.Inc()
+			log.Printf("UnexpectedDeviceDataConversionErr: %v: actual=%#v expected=%v (relative tolerance %v)\n", conversion.Name, lastValues[i].Value, expected, conversion.Tolerance)
+		}
+	}
+}
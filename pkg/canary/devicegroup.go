@@ -0,0 +1,138 @@
+/*
+ * Copyright (c) 2023 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package canary
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/SENERGY-Platform/device-repository/lib/model"
+	"github.com/SENERGY-Platform/models/go/models"
+	"github.com/SENERGY-Platform/snowflake-canary/pkg/devicemetadata"
+	"log"
+	"net/http"
+	"net/url"
+	"runtime/debug"
+	"slices"
+	"time"
+)
+
+// ensureDeviceGroup returns the canary device group, containing only the canary device, and checks the computed criteria
+func (this *Canary) ensureDeviceGroup(token string, info DeviceInfo) (groupId string, err error) {
+	this.metrics.DeviceGroupCheckCount.Inc()
+	this.metrics.DeviceRepoRequestCount.Inc()
+	start := time.Now()
+	groups, _, err, _ := this.devicerepo.ListDeviceGroups(token, model.DeviceGroupListOptions{
+		Limit:         1,
+		AttributeKeys: []string{devicemetadata.AttributeUsedForCanaryDeviceGroup},
+	})
+	this.metrics.DeviceRepoRequestLatencyMs.Set(float64(time.Since(start).Milliseconds()))
+	if err != nil {
+		this.metrics.DeviceRepoRequestErr.Inc()
+		log.Println("ERROR:", err)
+		debug.PrintStack()
+		return "", err
+	}
+	group := models.DeviceGroup{
+		Name:      "snowflake-canary-device-group",
+		DeviceIds: []string{info.Id},
+		Attributes: []models.Attribute{{
+			Key:    devicemetadata.AttributeUsedForCanaryDeviceGroup,
+			Value:  "true",
+			Origin: "canary",
+		}},
+	}
+	if len(groups) > 0 {
+		group.Id = groups[0].Id
+		group.Attributes = devicemetadata.WithCreatedAt(group.Attributes, groups[0].Attributes)
+		if !slices.Equal(groups[0].DeviceIds, group.DeviceIds) {
+			group, err = this.saveDeviceGroup(token, group)
+			if err != nil {
+				return "", err
+			}
+			time.Sleep(this.getChangeGuaranteeDuration())
+		}
+	} else {
+		group.Attributes = devicemetadata.WithCreatedAt(group.Attributes, nil)
+		group, err = this.saveDeviceGroup(token, group)
+		if err != nil {
+			return "", err
+		}
+		time.Sleep(this.getChangeGuaranteeDuration())
+	}
+	this.checkDeviceGroup(token, group.Id, info)
+	return group.Id, nil
+}
+
+// saveDeviceGroup creates the group if no id is set, otherwise the group is updated; criteria are computed by the platform
+func (this *Canary) saveDeviceGroup(token string, group models.DeviceGroup) (result models.DeviceGroup, err error) {
+	buf := &bytes.Buffer{}
+	err = json.NewEncoder(buf).Encode(group)
+	if err != nil {
+		return result, err
+	}
+	method := http.MethodPost
+	endpoint := this.config.DeviceManagerUrl + "/device-groups?wait=true"
+	if group.Id != "" {
+		method = http.MethodPut
+		endpoint = this.config.DeviceManagerUrl + "/device-groups/" + url.PathEscape(group.Id) + "?wait=true"
+	}
+	this.metrics.DeviceMetaUpdateCount.Inc()
+	req, err := http.NewRequest(method, endpoint, buf)
+	if err != nil {
+		return result, err
+	}
+	req.Header.Set("Authorization", token)
+	start := time.Now()
+	result, _, err = devicemetadata.Do[models.DeviceGroup](req)
+	this.metrics.DeviceMetaUpdateLatencyMs.Set(float64(time.Since(start).Milliseconds()))
+	if err != nil {
+		this.metrics.DeviceMetaUpdateErr.Inc()
+		log.Println("ERROR:", err)
+		debug.PrintStack()
+	}
+	return result, err
+}
+
+// checkDeviceGroup expects the canary device as the only member
+// and the criteria of the canary device type functions used by the canary processes and sensor checks
+func (this *Canary) checkDeviceGroup(token string, groupId string, info DeviceInfo) {
+	this.metrics.DeviceRepoRequestCount.Inc()
+	start := time.Now()
+	group, err, _ := this.devicerepo.ReadDeviceGroup(groupId, token, false)
+	this.metrics.DeviceRepoRequestLatencyMs.Set(float64(time.Since(start).Milliseconds()))
+	if err != nil {
+		this.metrics.DeviceRepoRequestErr.Inc()
+		log.Println("ERROR: checkDeviceGroup()", err)
+		return
+	}
+	if !slices.Equal(group.DeviceIds, []string{info.Id}) {
+		this.metrics.UnexpectedDeviceGroupErr.Inc()
+		log.Printf("UnexpectedDeviceGroupErr: device-ids=%#v; expected %#v\n", group.DeviceIds, []string{info.Id})
+	}
+	expectedCriteria := []models.DeviceGroupFilterCriteria{
+		{Interaction: models.REQUEST, FunctionId: this.config.CanaryCmdFunctionId, DeviceClassId: this.config.CanaryDeviceClassId},
+		{Interaction: models.REQUEST, FunctionId: this.config.CanaryCmdFunctionId2, DeviceClassId: this.config.CanaryDeviceClassId},
+		{Interaction: models.EVENT, FunctionId: this.config.CanarySensorFunctionId, AspectId: this.config.CanarySensorAspectId},
+		{Interaction: models.EVENT, FunctionId: this.config.CanarySensorFunctionId2, AspectId: this.config.CanarySensorAspectId2},
+	}
+	for _, expected := range expectedCriteria {
+		if !slices.Contains(group.Criteria, expected) {
+			this.metrics.UnexpectedDeviceGroupErr.Inc()
+			log.Printf("UnexpectedDeviceGroupErr: missing criteria %#v in %#v\n", expected, group.Criteria)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+/*
+ * Copyright (c) 2023 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package canary
+
+import (
+	"github.com/SENERGY-Platform/device-repository/lib/model"
+	"github.com/SENERGY-Platform/models/go/models"
+	"github.com/SENERGY-Platform/snowflake-canary/pkg/devicemetadata"
+	"github.com/google/uuid"
+	"log"
+	"math/rand"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+)
+
+func (this *Canary) testDeviceLifecycle(wg *sync.WaitGroup, token string, info DeviceInfo) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		this.checkDeviceLifecycle(token, info)
+	}()
+}
+
+// checkDeviceLifecycle uses a temporary device of the canary device type and a temporary hub.
+// the device is deleted and recreated with the same local id; the recreated device must not inherit anything of the deleted device.
+// values published with the local id after the recreation must reach the recreated device, and must not be mixed with the values of the deleted device.
+func (this *Canary) checkDeviceLifecycle(token string, info DeviceInfo) {
+	this.metrics.DeviceLifecycleCount.Inc()
+	localId := "snowflake-canary-lifecycle_" + uuid.NewString()
+
+	device, err := this.createTemporaryDevice(token, localId, info.DeviceTypeId)
+	if err != nil {
+		return
+	}
+	hub, err := this.createHub(token, HubInfo{Name: devicemetadata.TemporaryHubName(this.config.CanaryHubName, devicemetadata.TemporaryHubDeviceLifecycle), DeviceLocalIds: []string{localId}})
+	if err != nil {
+		this.devicemeta.DeleteDevice(token, device.Id)
+		return
+	}
+	defer this.deleteHub(token, hub.Id)
+	time.Sleep(this.getChangeGuaranteeDuration())
+
+	//publish
+	conn, err := this.connect(hub.Id)
+	if err != nil {
+		this.devicemeta.DeleteDevice(token, device.Id)
+		return
+	}
+	value1, value2 := rand.Intn(1000000), rand.Intn(1000000)
+	_, err = this.publish(device, conn, value1, value2)
+	this.disconnect(conn)
+	if err != nil {
+		this.devicemeta.DeleteDevice(token, device.Id)
+		return
+	}
+	time.Sleep(this.getChangeGuaranteeDuration())
+	this.checkDeviceLifecycleLastValues(token, device, []interface{}{devicemetadata.JsonNormalize(value1), devicemetadata.JsonNormalize(value2)})
+
+	//delete
+	err = this.devicemeta.DeleteDevice(token, device.Id)
+	if err != nil {
+		return
+	}
+	time.Sleep(this.getChangeGuaranteeDuration())
+	this.checkDeviceLifecycleDeleted(token, device, hub.Id)
+	this.checkDeviceLifecycleLastValues(token, device, nil)
+
+	//recreate with same local id
+	recreated, err := this.createTemporaryDevice(token, localId, info.DeviceTypeId)
+	if err != nil {
+		return
+	}
+	defer this.devicemeta.DeleteDevice(token, recreated.Id)
+	time.Sleep(this.getChangeGuaranteeDuration())
+	if recreated.Id == device.Id {
+		this.metrics.UnexpectedDeviceLifecycleErr.Inc()
+		log.Println("UnexpectedDeviceLifecycleErr: recreated device reuses the id of the deleted device", device.Id)
+	}
+	this.checkDeviceLifecycleLastValues(token, recreated, nil)
+	this.checkHubWithoutDevice(token, hub.Id, recreated)
+
+	//publish again with the same local id
+	_, err = this.updateHub(token, HubInfo{Id: hub.Id, Name: hub.Name, DeviceLocalIds: []string{localId}})
+	if err != nil {
+		return
+	}
+	time.Sleep(this.getChangeGuaranteeDuration())
+	conn, err = this.connect(hub.Id)
+	if err != nil {
+		return
+	}
+	value3, value4 := (value1+1+rand.Intn(999999))%1000000, (value2+1+rand.Intn(999999))%1000000 //never equal to the old values
+	_, err = this.publish(recreated, conn, value3, value4)
+	this.disconnect(conn)
+	if err != nil {
+		return
+	}
+	time.Sleep(this.getChangeGuaranteeDuration())
+	this.checkDeviceLifecycleLastValues(token, recreated, []interface{}{devicemetadata.JsonNormalize(value3), devicemetadata.JsonNormalize(value4)})
+	this.checkDeviceLifecycleLastValues(token, device, nil)
+}
+
+func (this *Canary) createTemporaryDevice(token string, localId string, deviceTypeId string) (device DeviceInfo, err error) {
+	return this.devicemeta.CreateDevice(token, DeviceInfo{
+		LocalId: localId,
+		Name:    "snowflake-canary-lifecycle-" + time.Now().String(),
+		Attributes: []models.Attribute{{
+			Key:    devicemetadata.AttributeUsedForTemporaryCanaryDevice,
+			Value:  "true",
+			Origin: "canary",
+		}},
+		DeviceTypeId: deviceTypeId,
+	})
+}
+
+// checkDeviceLifecycleLastValues expects the given values, or no values if expected is nil
+func (this *Canary) checkDeviceLifecycleLastValues(token string, device DeviceInfo, expected []interface{}) {
+	serviceId, err := this.getSensorServiceId(token, device)
+	if err != nil {
+		return
+	}
+	this.metrics.DeviceDataRequestCount.Inc()
+	start := time.Now()
+	lastValues, err := this.queryLastValues(token, device, serviceId)
+	this.metrics.DeviceDataRequestLatencyMs.Set(float64(time.Since(start).Milliseconds()))
+	if expected == nil {
+		if err != nil {
+			return //an error is an acceptable answer for unknown or deleted devices
+		}
+		for _, lastValue := range lastValues {
+			if lastValue.Value != nil {
+				this.metrics.UnexpectedDeviceLifecycleErr.Inc()
+				log.Printf("UnexpectedDeviceLifecycleErr: device %v (%v) has last values %#v; expected none\n", device.Id, device.LocalId, lastValues)
+				return
+			}
+		}
+		return
+	}
+	if err != nil {
+		this.metrics.DeviceDataRequestErr.Inc()
+		log.Println("ERROR: checkDeviceLifecycleLastValues()", err)
+		return
+	}
+	actual := []interface{}{}
+	for _, lastValue := range lastValues {
+		actual = append(actual, lastValue.Value)
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		this.metrics.UnexpectedDeviceLifecycleErr.Inc()
+		log.Printf("UnexpectedDeviceLifecycleErr: device %v (%v) has last values %#v; expected %#v\n", device.Id, device.LocalId, actual, expected)
+	}
+}
+
+func (this *Canary) checkDeviceLifecycleDeleted(token string, device DeviceInfo, hubId string) {
+	this.metrics.DeviceRepoRequestCount.Inc()
+	start := time.Now()
+	_, err, code := this.devicerepo.ReadDevice(device.Id, token, model.READ)
+	this.metrics.DeviceRepoRequestLatencyMs.Set(float64(time.Since(start).Milliseconds()))
+	if code != http.StatusNotFound {
+		if err != nil {
+			this.metrics.DeviceRepoRequestErr.Inc()
+			log.Println("ERROR: checkDeviceLifecycleDeleted()", err)
+		} else {
+			this.metrics.UnexpectedDeviceLifecycleErr.Inc()
+			log.Println("UnexpectedDeviceLifecycleErr: device still exists after delete", device.Id)
+		}
+	}
+	this.checkHubWithoutDevice(token, hubId, device)
+}
+
+func (this *Canary) checkHubWithoutDevice(token string, hubId string, device DeviceInfo) {
+	this.metrics.DeviceRepoRequestCount.Inc()
+	start := time.Now()
+	hub, err, _ := this.devicerepo.ReadHub(hubId, token, model.READ)
+	this.metrics.DeviceRepoRequestLatencyMs.Set(float64(time.Since(start).Milliseconds()))
+	if err != nil {
+		this.metrics.DeviceRepoRequestErr.Inc()
+		log.Println("ERROR: checkHubWithoutDevice()", err)
+		return
+	}
+	if contains(hub.DeviceLocalIds, device.LocalId) || contains(hub.DeviceIds, device.Id) {
+		this.metrics.UnexpectedDeviceLifecycleErr.Inc()
+		log.Printf("UnexpectedDeviceLifecycleErr: hub %v still references device %v (%v): local-ids=%#v ids=%#v\n", hubId, device.Id, device.LocalId, hub.DeviceLocalIds, hub.DeviceIds)
+	}
+}
@@ -30,3 +30,11 @@ func (this *Canary) testMetadata(wg *sync.WaitGroup, token string, info DeviceIn
 		this.devicemeta.TestMetadata(token, info)
 	}()
 }
+
+func (this *Canary) testValidation(wg *sync.WaitGroup, token string, info DeviceInfo) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		this.devicemeta.TestValidation(token, info)
+	}()
+}
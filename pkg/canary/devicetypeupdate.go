@@ -0,0 +1,132 @@
+/*
+ * Copyright (c) 2023 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package canary
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/SENERGY-Platform/snowflake-canary/pkg/devicemetadata"
+	"log"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+)
+
+func (this *Canary) testDeviceTypeUpdate(wg *sync.WaitGroup, token string) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		this.checkDeviceTypeUpdate(token)
+	}()
+}
+
+// checkDeviceTypeUpdate switches the area output of the secondary canary device type between a plain and a structured shape.
+// after the switch, values are published in the new shape until they are queryable in the new column;
+// the time between the update response and the first queryable value is exported.
+func (this *Canary) checkDeviceTypeUpdate(token string) {
+	this.metrics.DeviceTypeUpdateCheckCount.Inc()
+	dt, err := this.devicemeta.EnsureSecondaryDeviceType(token)
+	if err != nil {
+		return
+	}
+	device, err := this.devicemeta.EnsureSecondaryDevice(token, dt.Id)
+	if err != nil {
+		return
+	}
+	serviceId := ""
+	for _, service := range dt.Services {
+		if service.LocalId == devicemetadata.SensorServiceLocalId {
+			serviceId = service.Id
+		}
+	}
+
+	hub, err := this.createHub(token, HubInfo{Name: devicemetadata.TemporaryHubName(this.config.CanaryHubName, devicemetadata.TemporaryHubDeviceTypeUpdate), DeviceLocalIds: []string{device.LocalId}})
+	if err != nil {
+		return
+	}
+	defer this.deleteHub(token, hub.Id)
+	time.Sleep(this.getChangeGuaranteeDuration())
+
+	conn, err := this.connect(hub.Id)
+	if err != nil {
+		return
+	}
+	defer this.disconnect(conn)
+
+	structured := !this.devicemeta.HasStructuredArea(dt)
+	column := "area"
+	if structured {
+		column = "area." + devicemetadata.SecondaryAreaStructValueName
+	}
+
+	err = this.devicemeta.SwitchSecondaryDeviceType(token, dt, structured)
+	if err != nil {
+		return
+	}
+	switchedAt := time.Now()
+
+	//values of earlier iterations may become queryable later
+	published := map[string]bool{}
+	timeout := time.After(this.deviceTypeUpdatePropagationTimeout)
+	ticker := time.NewTicker(this.deviceTypeUpdatePollInterval)
+	defer ticker.Stop()
+	for {
+		value := rand.Intn(1000000)
+		msg, err := getDeviceTypeUpdateMessage(this.config.CanaryProtocolSegmentName2, value, structured)
+		if err != nil {
+			this.metrics.UncategorizedErr.Inc()
+			return
+		}
+		_, err = this.publishMessage(device, conn, msg)
+		if err != nil {
+			return
+		}
+		published[fmt.Sprint(devicemetadata.JsonNormalize(value))] = true
+		select {
+		case <-timeout:
+			this.metrics.DeviceTypeUpdatePropagationTimeoutErr.Inc()
+			log.Printf("ERROR: DeviceTypeUpdatePropagationTimeoutErr: values in new shape (structured=%v) not queryable in column %v after %v\n", structured, column, this.deviceTypeUpdatePropagationTimeout)
+			return
+		case <-ticker.C:
+		}
+		this.metrics.DeviceDataRequestCount.Inc()
+		start := time.Now()
+		lastValues, err := this.queryLastValueColumns(token, device.Id, serviceId, column)
+		this.metrics.DeviceDataRequestLatencyMs.Set(float64(time.Since(start).Milliseconds()))
+		if err != nil {
+			this.metrics.DeviceDataRequestErr.Inc()
+		}
+		if err == nil && len(lastValues) == 1 && lastValues[0].Value != nil && published[fmt.Sprint(lastValues[0].Value)] {
+			this.metrics.DeviceTypeUpdatePropagationLatencyMs.Observe(float64(time.Since(switchedAt).Milliseconds()))
+			return
+		}
+	}
+}
+
+// getDeviceTypeUpdateMessage creates a message which is only valid for the requested area shape
+func getDeviceTypeUpdateMessage(segmentName string, value int, structured bool) (payload []byte, err error) {
+	area := strconv.Itoa(value)
+	if structured {
+		temp, err := json.Marshal(map[string]int{devicemetadata.SecondaryAreaStructValueName: value})
+		if err != nil {
+			return payload, err
+		}
+		area = string(temp)
+	}
+	return json.Marshal(map[string]string{segmentName: area})
+}
@@ -0,0 +1,264 @@
+/*
+ * Copyright (c) 2023 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package canary
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/SENERGY-Platform/snowflake-canary/pkg/devicemetadata"
+	"log"
+	"math/rand"
+	"net/http"
+	"reflect"
+	"runtime/debug"
+	"time"
+)
+
+const historySequenceLength = 5
+const historySequenceTimeStep = 100 * time.Millisecond
+const historyAggregationWindow = time.Hour
+
+type TimescaleQuery struct {
+	DeviceId         string                 `json:"deviceId"`
+	ServiceId        string                 `json:"serviceId"`
+	Columns          []TimescaleQueryColumn `json:"columns"`
+	Time             *TimescaleQueryTime    `json:"time,omitempty"`
+	Limit            *int                   `json:"limit,omitempty"`
+	GroupTime        *string                `json:"groupTime,omitempty"`
+	OrderColumnIndex *int                   `json:"orderColumnIndex,omitempty"`
+	OrderDirection   *string                `json:"orderDirection,omitempty"`
+}
+
+type TimescaleQueryColumn struct {
+	Name      string  `json:"name"`
+	GroupType *string `json:"groupType,omitempty"`
+}
+
+type TimescaleQueryTime struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// TimescaleQueryResult contains one table per query; each row starts with the time column
+type TimescaleQueryResult = [][][]interface{}
+
+type HistoryValue struct {
+	Value1 int
+	Value2 int
+	Time   time.Time
+}
+
+// testHistory publishes a sequence of values with explicit value times and checks them against the historic query api.
+func (this *Canary) testHistory(token string, info DeviceInfo, hubId string) {
+	this.metrics.DeviceHistoryRequestCount.Inc()
+	serviceId, err := this.getSensorServiceId(token, info)
+	if err != nil {
+		return
+	}
+
+	conn, err := this.connect(hubId)
+	if err != nil {
+		return
+	}
+	sequence, start, end, err := this.publishHistorySequence(info, conn)
+	this.disconnect(conn)
+	if err != nil {
+		return
+	}
+
+	time.Sleep(this.getChangeGuaranteeDuration())
+
+	this.checkHistoryRaw(token, info, serviceId, sequence, start, end)
+	this.checkHistoryLimitAndOrder(token, info, serviceId, sequence, start, end)
+	this.checkHistoryAggregates(token, info, serviceId, sequence, start, end)
+}
+
+// publishHistorySequence publishes historySequenceLength values with explicit, distinct timestamps.
+// the timestamps lie in a single aggregation window; the returned time range contains exactly the published timestamps.
+func (this *Canary) publishHistorySequence(info DeviceInfo, conn *Conn) (sequence []HistoryValue, start time.Time, end time.Time, err error) {
+	start, end = getHistorySequenceRange(time.Now())
+	base := rand.Intn(1000000)
+	for i := 0; i < historySequenceLength; i++ {
+		value := HistoryValue{Value1: base + i, Value2: base - i, Time: start.Add(time.Duration(i) * historySequenceTimeStep)}
+		_, err = this.publishWithTime(info, conn, value.Value1, value.Value2, value.Time)
+		if err != nil {
+			return sequence, start, end, err
+		}
+		sequence = append(sequence, value)
+	}
+	return sequence, start, end, nil
+}
+
+// getHistorySequenceRange returns the time range of a sequence starting at now;
+// a sequence which would cross an aggregation window is moved back to end in the window of now.
+func getHistorySequenceRange(now time.Time) (start time.Time, end time.Time) {
+	start = now.UTC().Truncate(time.Millisecond)
+	length := time.Duration(historySequenceLength-1) * historySequenceTimeStep
+	end = start.Add(length)
+	if !start.Truncate(historyAggregationWindow).Equal(end.Truncate(historyAggregationWindow)) {
+		end = end.Truncate(historyAggregationWindow).Add(-time.Millisecond)
+		start = end.Add(-length)
+	}
+	return start, end
+}
+
+// getHistoryQueryTime returns the range of the published sequence, padded by half a time step.
+// the padding does not reach other values of the sequence grid and makes the check independent of inclusive or exclusive range bounds.
+func getHistoryQueryTime(start time.Time, end time.Time) *TimescaleQueryTime {
+	return &TimescaleQueryTime{
+		Start: start.Add(-historySequenceTimeStep / 2).Format(time.RFC3339Nano),
+		End:   end.Add(historySequenceTimeStep / 2).Format(time.RFC3339Nano),
+	}
+}
+
+func (this *Canary) checkHistoryRaw(token string, info DeviceInfo, serviceId string, sequence []HistoryValue, start time.Time, end time.Time) {
+	orderColumnIndex := 0
+	orderDirection := "asc"
+	table, err := this.queryHistory(token, TimescaleQuery{
+		DeviceId:         info.Id,
+		ServiceId:        serviceId,
+		Columns:          []TimescaleQueryColumn{{Name: "measurements.measurement.value"}, {Name: "area"}},
+		Time:             getHistoryQueryTime(start, end),
+		OrderColumnIndex: &orderColumnIndex,
+		OrderDirection:   &orderDirection,
+	})
+	if err != nil {
+		return
+	}
+	expected := [][]interface{}{}
+	for _, v := range sequence {
+		expected = append(expected, []interface{}{devicemetadata.JsonNormalize(v.Value1), devicemetadata.JsonNormalize(v.Value2)})
+	}
+	this.compareHistory("raw", withoutTimeColumn(table), expected)
+}
+
+func (this *Canary) checkHistoryLimitAndOrder(token string, info DeviceInfo, serviceId string, sequence []HistoryValue, start time.Time, end time.Time) {
+	limit := 2
+	orderColumnIndex := 0
+	orderDirection := "desc"
+	table, err := this.queryHistory(token, TimescaleQuery{
+		DeviceId:         info.Id,
+		ServiceId:        serviceId,
+		Columns:          []TimescaleQueryColumn{{Name: "measurements.measurement.value"}},
+		Time:             getHistoryQueryTime(start, end),
+		Limit:            &limit,
+		OrderColumnIndex: &orderColumnIndex,
+		OrderDirection:   &orderDirection,
+	})
+	if err != nil {
+		return
+	}
+	expected := [][]interface{}{}
+	for i := len(sequence) - 1; i >= 0 && len(expected) < limit; i-- {
+		expected = append(expected, []interface{}{devicemetadata.JsonNormalize(sequence[i].Value1)})
+	}
+	this.compareHistory("limit/order", withoutTimeColumn(table), expected)
+}
+
+// checkHistoryAggregates expects exactly one aggregated row, because publishHistorySequence keeps all values in one historyAggregationWindow
+func (this *Canary) checkHistoryAggregates(token string, info DeviceInfo, serviceId string, sequence []HistoryValue, start time.Time, end time.Time) {
+	groupTime := "1h"
+	meanGroup, minGroup, maxGroup := "mean", "min", "max"
+	table, err := this.queryHistory(token, TimescaleQuery{
+		DeviceId:  info.Id,
+		ServiceId: serviceId,
+		Columns: []TimescaleQueryColumn{
+			{Name: "measurements.measurement.value", GroupType: &meanGroup},
+			{Name: "measurements.measurement.value", GroupType: &minGroup},
+			{Name: "measurements.measurement.value", GroupType: &maxGroup},
+		},
+		Time:      getHistoryQueryTime(start, end),
+		GroupTime: &groupTime,
+	})
+	if err != nil {
+		return
+	}
+	sum, minValue, maxValue := 0, sequence[0].Value1, sequence[0].Value1
+	for _, v := range sequence {
+		sum = sum + v.Value1
+		minValue = min(minValue, v.Value1)
+		maxValue = max(maxValue, v.Value1)
+	}
+	expected := [][]interface{}{{
+		devicemetadata.JsonNormalize(float64(sum) / float64(len(sequence))),
+		devicemetadata.JsonNormalize(minValue),
+		devicemetadata.JsonNormalize(maxValue),
+	}}
+	this.compareHistory("aggregates", withoutEmptyRows(withoutTimeColumn(table)), expected)
+}
+
+func (this *Canary) queryHistory(token string, query TimescaleQuery) (table [][]interface{}, err error) {
+	defer func() {
+		if err != nil {
+			this.metrics.DeviceHistoryRequestErr.Inc()
+			log.Println("ERROR: queryHistory()", err)
+			debug.PrintStack()
+		}
+	}()
+	buf := &bytes.Buffer{}
+	err = json.NewEncoder(buf).Encode([]TimescaleQuery{query})
+	if err != nil {
+		return table, err
+	}
+	req, err := http.NewRequest(http.MethodPost, this.config.TimescaleQueryUrl+"?format=per_query", buf)
+	if err != nil {
+		return table, err
+	}
+	req.Header.Set("Authorization", token)
+	start := time.Now()
+	result, _, err := devicemetadata.Do[TimescaleQueryResult](req)
+	this.metrics.DeviceHistoryRequestLatencyMs.Set(float64(time.Since(start).Milliseconds()))
+	if err != nil {
+		return table, err
+	}
+	if len(result) != 1 {
+		return table, fmt.Errorf("unexpected query result count %v", len(result))
+	}
+	return result[0], nil
+}
+
+func (this *Canary) compareHistory(name string, actual [][]interface{}, expected [][]interface{}) {
+	if !reflect.DeepEqual(devicemetadata.JsonNormalize(actual), devicemetadata.JsonNormalize(expected)) {
+		this.metrics.UnexpectedDeviceHistoryErr.Inc()
+		log.Printf("UnexpectedDeviceHistoryErr: %v: actual=%#v expected=%#v\n", name, actual, expected)
+	}
+}
+
+func withoutTimeColumn(table [][]interface{}) (result [][]interface{}) {
+	result = [][]interface{}{}
+	for _, row := range table {
+		if len(row) > 0 {
+			result = append(result, row[1:])
+		}
+	}
+	return result
+}
+
+// withoutEmptyRows removes rows of empty aggregation windows
+func withoutEmptyRows(table [][]interface{}) (result [][]interface{}) {
+	result = [][]interface{}{}
+	for _, row := range table {
+		for _, cell := range row {
+			if cell != nil {
+				result = append(result, row)
+				break
+			}
+		}
+	}
+	return result
+}
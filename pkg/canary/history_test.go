@@ -0,0 +1,76 @@
+/*
+ * Copyright (c) 2023 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package canary
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetHistorySequenceRange(t *testing.T) {
+	length := time.Duration(historySequenceLength-1) * historySequenceTimeStep
+	window := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name          string
+		now           time.Time
+		expectedStart time.Time
+	}{
+		{
+			name:          "start of window",
+			now:           window,
+			expectedStart: window,
+		},
+		{
+			name:          "inside of window",
+			now:           window.Add(30 * time.Minute),
+			expectedStart: window.Add(30 * time.Minute),
+		},
+		{
+			name:          "truncated to milliseconds",
+			now:           window.Add(30*time.Minute + 1234567*time.Nanosecond),
+			expectedStart: window.Add(30*time.Minute + time.Millisecond),
+		},
+		{
+			name:          "ends exactly at window end",
+			now:           window.Add(historyAggregationWindow - length - time.Millisecond),
+			expectedStart: window.Add(historyAggregationWindow - length - time.Millisecond),
+		},
+		{
+			name:          "crossing window end is moved back",
+			now:           window.Add(historyAggregationWindow - historySequenceTimeStep),
+			expectedStart: window.Add(historyAggregationWindow - length - time.Millisecond),
+		},
+		{
+			name:          "other time zones",
+			now:           window.Add(30 * time.Minute).In(time.FixedZone("test", 2*60*60)),
+			expectedStart: window.Add(30 * time.Minute),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			start, end := getHistorySequenceRange(test.now)
+			if !start.Equal(test.expectedStart) || start.Location() != time.UTC {
+				t.Errorf("start=%v; expected %v", start, test.expectedStart)
+			}
+			if end.Sub(start) != length {
+				t.Errorf("end-start=%v; expected %v", end.Sub(start), length)
+			}
+			if !start.Truncate(historyAggregationWindow).Equal(end.Truncate(historyAggregationWindow)) {
+				t.Errorf("range %v - %v crosses an aggregation window", start, end)
+			}
+		})
+	}
+}
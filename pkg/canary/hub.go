@@ -66,7 +66,7 @@ func (this *Canary) listCanaryHubs(token string) (hubs []HubInfo, err error) {
 	start := time.Now()
 	temp, err, _ := this.devicerepo.ListHubs(token, client.HubListOptions{
 		Search: this.config.CanaryHubName,
-		Limit:  1,
+		Limit:  100,
 		Offset: 0,
 	})
 	this.metrics.DeviceRepoRequestCount.Inc()
@@ -78,6 +78,9 @@ func (this *Canary) listCanaryHubs(token string) (hubs []HubInfo, err error) {
 		return hubs, err
 	}
 	for _, hub := range temp {
+		if hub.Name != this.config.CanaryHubName {
+			continue //search may also find temporary hubs of other checks
+		}
 		hubs = append(hubs, HubInfo{
 			Id:             hub.Id,
 			Name:           hub.Name,
@@ -89,60 +92,84 @@ func (this *Canary) listCanaryHubs(token string) (hubs []HubInfo, err error) {
 }
 
 func (this *Canary) createCanaryHub(token string, device DeviceInfo) (hubId string, err error) {
-	hub := HubInfo{
+	hub, err := this.createHub(token, HubInfo{
 		Name:           this.config.CanaryHubName,
 		DeviceLocalIds: []string{device.LocalId},
-	}
+	})
+	time.Sleep(this.getChangeGuaranteeDuration()) //ensure device is finished creating
+	return hub.Id, err
+}
+
+func (this *Canary) updateCanaryHub(token string, hubId string, device DeviceInfo) (err error) {
+	_, err = this.updateHub(token, HubInfo{
+		Id:             hubId,
+		Name:           this.config.CanaryHubName,
+		DeviceLocalIds: []string{device.LocalId},
+	})
+	time.Sleep(this.getChangeGuaranteeDuration()) //ensure device is finished creating
+	return err
+}
 
+func (this *Canary) createHub(token string, hub HubInfo) (result HubInfo, err error) {
 	buf := &bytes.Buffer{}
 	err = json.NewEncoder(buf).Encode(hub)
 	if err != nil {
-		return "", err
+		return result, err
 	}
 	this.metrics.DeviceMetaUpdateCount.Inc()
 	req, err := http.NewRequest(http.MethodPost, this.config.DeviceManagerUrl+"/hubs", buf)
 	if err != nil {
-		return "", err
+		return result, err
 	}
 	req.Header.Set("Authorization", token)
 	start := time.Now()
-	hub, _, err = devicemetadata.Do[HubInfo](req)
+	result, _, err = devicemetadata.Do[HubInfo](req)
 	this.metrics.DeviceMetaUpdateLatencyMs.Set(float64(time.Since(start).Milliseconds()))
 	if err != nil {
 		this.metrics.DeviceMetaUpdateErr.Inc()
 		log.Println("ERROR:", err)
 		debug.PrintStack()
 	}
-	time.Sleep(this.getChangeGuaranteeDuration()) //ensure device is finished creating
-	return hub.Id, err
+	return result, err
 }
 
-func (this *Canary) updateCanaryHub(token string, hubId string, device DeviceInfo) (err error) {
-	hub := HubInfo{
-		Id:             hubId,
-		Name:           this.config.CanaryHubName,
-		DeviceLocalIds: []string{device.LocalId},
-	}
-
+func (this *Canary) updateHub(token string, hub HubInfo) (result HubInfo, err error) {
 	buf := &bytes.Buffer{}
 	err = json.NewEncoder(buf).Encode(hub)
 	if err != nil {
-		return err
+		return result, err
 	}
 	this.metrics.DeviceMetaUpdateCount.Inc()
 	req, err := http.NewRequest(http.MethodPut, this.config.DeviceManagerUrl+"/hubs/"+url.PathEscape(hub.Id), buf)
+	if err != nil {
+		return result, err
+	}
+	req.Header.Set("Authorization", token)
+	start := time.Now()
+	result, _, err = devicemetadata.Do[HubInfo](req)
+	this.metrics.DeviceMetaUpdateLatencyMs.Set(float64(time.Since(start).Milliseconds()))
+	if err != nil {
+		this.metrics.DeviceMetaUpdateErr.Inc()
+		log.Println("ERROR:", err)
+		debug.PrintStack()
+	}
+	return result, err
+}
+
+func (this *Canary) deleteHub(token string, hubId string) (err error) {
+	this.metrics.DeviceMetaUpdateCount.Inc()
+	req, err := http.NewRequest(http.MethodDelete, this.config.DeviceManagerUrl+"/hubs/"+url.PathEscape(hubId), nil)
 	if err != nil {
 		return err
 	}
 	req.Header.Set("Authorization", token)
 	start := time.Now()
-	hub, _, err = devicemetadata.Do[HubInfo](req)
+	_, err = devicemetadata.DoWithoutResult(req)
 	this.metrics.DeviceMetaUpdateLatencyMs.Set(float64(time.Since(start).Milliseconds()))
 	if err != nil {
 		this.metrics.DeviceMetaUpdateErr.Inc()
 		log.Println("ERROR:", err)
 		debug.PrintStack()
 	}
-	time.Sleep(this.getChangeGuaranteeDuration()) //ensure device is finished creating
 	return err
 }
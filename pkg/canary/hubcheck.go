@@ -0,0 +1,198 @@
+/*
+ * Copyright (c) 2023 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package canary
+
+import (
+	"github.com/SENERGY-Platform/device-repository/lib/client"
+	"github.com/SENERGY-Platform/device-repository/lib/model"
+	"github.com/SENERGY-Platform/models/go/models"
+	"github.com/SENERGY-Platform/snowflake-canary/pkg/devicemetadata"
+	"github.com/google/uuid"
+	"log"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+func (this *Canary) testHubLifecycle(wg *sync.WaitGroup, token string, info DeviceInfo) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		this.checkHubLifecycle(token, info)
+	}()
+}
+
+// checkHubLifecycle uses a temporary hub and a temporary device of the canary device type,
+// independent of the hub and device used by testDeviceConnection.
+// the temporary hub is connected without devices, to not influence the connection state of the temporary device.
+func (this *Canary) checkHubLifecycle(token string, info DeviceInfo) {
+	this.metrics.HubLifecycleCount.Inc()
+	device, err := this.createTemporaryDevice(token, "snowflake-canary-hub-lifecycle_"+uuid.NewString(), info.DeviceTypeId)
+	if err != nil {
+		return
+	}
+	defer this.devicemeta.DeleteDevice(token, device.Id)
+	hub, err := this.createHub(token, HubInfo{Name: devicemetadata.TemporaryHubName(this.config.CanaryHubName, devicemetadata.TemporaryHubLifecycle)})
+	if err != nil {
+		return
+	}
+	defer func() {
+		err = this.deleteHub(token, hub.Id)
+		if err != nil {
+			return
+		}
+		time.Sleep(this.getChangeGuaranteeDuration())
+		this.checkHubDeleted(token, hub.Id)
+	}()
+	time.Sleep(this.getChangeGuaranteeDuration())
+	this.checkHubDevices(token, hub.Id, device, false)
+
+	//add device
+	hub.DeviceLocalIds = []string{device.LocalId}
+	_, err = this.updateHub(token, hub)
+	if err != nil {
+		return
+	}
+	time.Sleep(this.getChangeGuaranteeDuration())
+	this.checkHubDevices(token, hub.Id, device, true)
+
+	//remove device
+	hub.DeviceLocalIds = []string{}
+	_, err = this.updateHub(token, hub)
+	if err != nil {
+		return
+	}
+	time.Sleep(this.getChangeGuaranteeDuration())
+	this.checkHubDevices(token, hub.Id, device, false)
+
+	//connection state
+	conn, err := this.connect(hub.Id)
+	if err != nil {
+		return
+	}
+	time.Sleep(this.getChangeGuaranteeDuration())
+	this.checkHubConnState(token, hub.Id, true)
+	this.disconnect(conn)
+	time.Sleep(this.getChangeGuaranteeDuration())
+	this.checkHubConnState(token, hub.Id, false)
+}
+
+// checkHubDevices compares the hub read and the hub list results with the expected device list
+func (this *Canary) checkHubDevices(token string, hubId string, info DeviceInfo, expectDevice bool) {
+	expectedLocalIds := []string{}
+	expectedIds := []string{}
+	if expectDevice {
+		expectedLocalIds = []string{info.LocalId}
+		expectedIds = []string{info.Id}
+	}
+
+	this.metrics.DeviceRepoRequestCount.Inc()
+	start := time.Now()
+	hub, err, _ := this.devicerepo.ReadHub(hubId, token, model.READ)
+	this.metrics.DeviceRepoRequestLatencyMs.Set(float64(time.Since(start).Milliseconds()))
+	if err != nil {
+		this.metrics.DeviceRepoRequestErr.Inc()
+		log.Println("ERROR: checkHubDevices() ReadHub", err)
+		return
+	}
+	if !equalStringSets(hub.DeviceLocalIds, expectedLocalIds) || !equalStringSets(hub.DeviceIds, expectedIds) {
+		this.metrics.UnexpectedHubMetadataErr.Inc()
+		log.Printf("UnexpectedHubMetadataErr: ReadHub() local-ids=%#v ids=%#v; expected local-ids=%#v ids=%#v\n", hub.DeviceLocalIds, hub.DeviceIds, expectedLocalIds, expectedIds)
+	}
+
+	this.metrics.DeviceRepoRequestCount.Inc()
+	start = time.Now()
+	hubs, err, _ := this.devicerepo.ListHubs(token, client.HubListOptions{Ids: []string{hubId}})
+	this.metrics.DeviceRepoRequestLatencyMs.Set(float64(time.Since(start).Milliseconds()))
+	if err != nil {
+		this.metrics.DeviceRepoRequestErr.Inc()
+		log.Println("ERROR: checkHubDevices() ListHubs", err)
+		return
+	}
+	if len(hubs) != 1 {
+		this.metrics.UnexpectedHubMetadataErr.Inc()
+		log.Printf("UnexpectedHubMetadataErr: ListHubs() returned %v hubs for id %v\n", len(hubs), hubId)
+	} else if !equalStringSets(hubs[0].DeviceLocalIds, expectedLocalIds) || !equalStringSets(hubs[0].DeviceIds, expectedIds) {
+		this.metrics.UnexpectedHubMetadataErr.Inc()
+		log.Printf("UnexpectedHubMetadataErr: ListHubs() local-ids=%#v ids=%#v; expected local-ids=%#v ids=%#v\n", hubs[0].DeviceLocalIds, hubs[0].DeviceIds, expectedLocalIds, expectedIds)
+	}
+
+	//the device may be part of other hubs, only the presence of the temporary hub is relevant
+	this.metrics.DeviceRepoRequestCount.Inc()
+	start = time.Now()
+	hubsOfDevice, err, _ := this.devicerepo.ListHubs(token, client.HubListOptions{LocalDeviceId: info.LocalId, Limit: 100})
+	this.metrics.DeviceRepoRequestLatencyMs.Set(float64(time.Since(start).Milliseconds()))
+	if err != nil {
+		this.metrics.DeviceRepoRequestErr.Inc()
+		log.Println("ERROR: checkHubDevices() ListHubs", err)
+		return
+	}
+	found := slices.ContainsFunc(hubsOfDevice, func(h models.Hub) bool {
+		return h.Id == hubId
+	})
+	if found != expectDevice {
+		this.metrics.UnexpectedHubMetadataErr.Inc()
+		log.Printf("UnexpectedHubMetadataErr: ListHubs(local-device-id=%v) contains hub %v = %v; expected %v\n", info.LocalId, hubId, found, expectDevice)
+	}
+}
+
+func (this *Canary) checkHubConnState(token string, hubId string, expectedConnState bool) {
+	this.metrics.DeviceRepoRequestCount.Inc()
+	start := time.Now()
+	hub, err, _ := this.devicerepo.ReadExtendedHub(hubId, token, model.READ)
+	this.metrics.DeviceRepoRequestLatencyMs.Set(float64(time.Since(start).Milliseconds()))
+	if err != nil {
+		log.Println("ERROR: checkHubConnState()", err)
+		this.metrics.DeviceRepoRequestErr.Inc()
+		return
+	}
+	if (hub.ConnectionState == models.ConnectionStateOnline) != expectedConnState {
+		log.Printf("Unexpected hub connection-state: actual(%#v); expected(connected=%#v)\n", hub.ConnectionState, expectedConnState)
+		if expectedConnState {
+			this.metrics.UnexpectedHubOfflineStateErr.Inc()
+		} else {
+			this.metrics.UnexpectedHubOnlineStateErr.Inc()
+		}
+	}
+}
+
+func (this *Canary) checkHubDeleted(token string, hubId string) {
+	this.metrics.DeviceRepoRequestCount.Inc()
+	start := time.Now()
+	_, err, code := this.devicerepo.ReadHub(hubId, token, model.READ)
+	this.metrics.DeviceRepoRequestLatencyMs.Set(float64(time.Since(start).Milliseconds()))
+	if code == http.StatusNotFound {
+		return
+	}
+	if err != nil {
+		this.metrics.DeviceRepoRequestErr.Inc()
+		log.Println("ERROR: checkHubDeleted()", err)
+		return
+	}
+	this.metrics.UnexpectedHubMetadataErr.Inc()
+	log.Println("UnexpectedHubMetadataErr: hub still exists after delete", hubId)
+}
+
+// equalStringSets compares sorted copies, so duplicates have to match too
+func equalStringSets(a []string, b []string) bool {
+	a = slices.Clone(a)
+	b = slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
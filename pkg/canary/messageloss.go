@@ -0,0 +1,122 @@
+/*
+ * Copyright (c) 2023 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package canary
+
+import (
+	"log"
+	"math/rand"
+	"time"
+)
+
+// testMessageLoss publishes MessageLossBurstSize measurements with the sequence number in the area segment
+// and reads them back from the history to detect lost, duplicated and reordered messages.
+func (this *Canary) testMessageLoss(token string, info DeviceInfo, hubId string) {
+	if this.config.MessageLossBurstSize <= 0 {
+		return
+	}
+	this.metrics.MessageLossCheckCount.Inc()
+	serviceId, err := this.getSensorServiceId(token, info)
+	if err != nil {
+		return
+	}
+
+	conn, err := this.connect(hubId)
+	if err != nil {
+		return
+	}
+	marker := rand.Intn(1000000)
+	start := time.Now()
+	for seq := 0; seq < this.config.MessageLossBurstSize; seq++ {
+		_, err = this.publish(info, conn, marker, seq)
+		if err != nil {
+			break
+		}
+	}
+	end := time.Now()
+	this.disconnect(conn)
+	if err != nil {
+		return
+	}
+
+	time.Sleep(this.getChangeGuaranteeDuration())
+
+	limit := 2 * this.config.MessageLossBurstSize //leave room for duplicates
+	orderColumnIndex := 0
+	orderDirection := "asc"
+	table, err := this.queryHistory(token, TimescaleQuery{
+		DeviceId:  info.Id,
+		ServiceId: serviceId,
+		Columns:   []TimescaleQueryColumn{{Name: "measurements.measurement.value"}, {Name: "area"}},
+		Time: &TimescaleQueryTime{
+			Start: start.Add(-this.lastValueTimeTolerance).Format(time.RFC3339Nano),
+			End:   end.Add(this.lastValueTimeTolerance).Format(time.RFC3339Nano),
+		},
+		Limit:            &limit,
+		OrderColumnIndex: &orderColumnIndex,
+		OrderDirection:   &orderDirection,
+	})
+	if err != nil {
+		return
+	}
+
+	lost, duplicates, outOfOrder := evaluateMessageSequence(table, marker, this.config.MessageLossBurstSize)
+	this.metrics.MessageLossRatio.Set(float64(lost) / float64(this.config.MessageLossBurstSize))
+	this.metrics.MessageLossDuplicates.Add(float64(duplicates))
+	this.metrics.MessageLossOutOfOrder.Add(float64(outOfOrder))
+	if lost > 0 || duplicates > 0 || outOfOrder > 0 {
+		log.Printf("ERROR: testMessageLoss() burst=%v lost=%v duplicates=%v out-of-order=%v\n", this.config.MessageLossBurstSize, lost, duplicates, outOfOrder)
+	}
+}
+
+// evaluateMessageSequence expects rows of [time, marker, seq] ordered by time.
+// rows with equal timestamps are not counted as out of order.
+func evaluateMessageSequence(table [][]interface{}, marker int, burstSize int) (lost int, duplicates int, outOfOrder int) {
+	received := map[int]int{}
+	prevSeq := -1
+	prevTime := ""
+	for _, row := range table {
+		if len(row) != 3 {
+			continue
+		}
+		rowMarker, ok := row[1].(float64)
+		if !ok || int(rowMarker) != marker {
+			continue
+		}
+		rowSeq, ok := row[2].(float64)
+		if !ok {
+			continue
+		}
+		seq := int(rowSeq)
+		rowTime, _ := row[0].(string)
+		received[seq] = received[seq] + 1
+		if seq < prevSeq && rowTime != prevTime {
+			outOfOrder++
+		}
+		prevSeq = seq
+		prevTime = rowTime
+	}
+	for seq := 0; seq < burstSize; seq++ {
+		count := received[seq]
+		if count == 0 {
+			lost++
+		}
+		if count > 1 {
+			duplicates = duplicates + count - 1
+		}
+	}
+	return lost, duplicates, outOfOrder
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright (c) 2023 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package canary
+
+import (
+	"testing"
+)
+
+func TestEvaluateMessageSequence(t *testing.T) {
+	row := func(time string, marker float64, seq float64) []interface{} {
+		return []interface{}{time, marker, seq}
+	}
+	tests := []struct {
+		name               string
+		table              [][]interface{}
+		burstSize          int
+		expectedLost       int
+		expectedDuplicates int
+		expectedOutOfOrder int
+	}{
+		{
+			name:      "complete",
+			table:     [][]interface{}{row("t1", 7, 0), row("t2", 7, 1), row("t3", 7, 2)},
+			burstSize: 3,
+		},
+		{
+			name:         "empty",
+			table:        [][]interface{}{},
+			burstSize:    3,
+			expectedLost: 3,
+		},
+		{
+			name:         "lost",
+			table:        [][]interface{}{row("t1", 7, 0), row("t3", 7, 2)},
+			burstSize:    3,
+			expectedLost: 1,
+		},
+		{
+			name:               "duplicates",
+			table:              [][]interface{}{row("t1", 7, 0), row("t2", 7, 1), row("t3", 7, 1), row("t4", 7, 1), row("t5", 7, 2)},
+			burstSize:          3,
+			expectedDuplicates: 2,
+		},
+		{
+			name:               "out of order",
+			table:              [][]interface{}{row("t1", 7, 1), row("t2", 7, 0), row("t3", 7, 2)},
+			burstSize:          3,
+			expectedOutOfOrder: 1,
+		},
+		{
+			name:      "equal times are not out of order",
+			table:     [][]interface{}{row("t1", 7, 1), row("t1", 7, 0), row("t2", 7, 2)},
+			burstSize: 3,
+		},
+		{
+			name:         "other markers and invalid rows are ignored",
+			table:        [][]interface{}{row("t1", 7, 0), row("t2", 8, 1), {"t3", 7.0}, {"t4", 7.0, "2"}, row("t5", 7, 2)},
+			burstSize:    3,
+			expectedLost: 1,
+		},
+		{
+			name:         "sequence numbers outside of the burst are not counted",
+			table:        [][]interface{}{row("t1", 7, 0), row("t2", 7, 5)},
+			burstSize:    2,
+			expectedLost: 1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lost, duplicates, outOfOrder := evaluateMessageSequence(test.table, 7, test.burstSize)
+			if lost != test.expectedLost || duplicates != test.expectedDuplicates || outOfOrder != test.expectedOutOfOrder {
+				t.Errorf("lost=%v duplicates=%v out-of-order=%v; expected lost=%v duplicates=%v out-of-order=%v", lost, duplicates, outOfOrder, test.expectedLost, test.expectedDuplicates, test.expectedOutOfOrder)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+/*
+ * Copyright (c) 2023 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package canary
+
+import (
+	"log"
+	"math/rand"
+)
+
+// testMultiTaskProcess publishes a dedicated sensor value and starts the multi task process, after the value is queryable as last value.
+// the sign of the value alternates between runs, so that both branches of the process are checked.
+func (this *Canary) testMultiTaskProcess(token string, info DeviceInfo, hubId string) {
+	negative := !this.multiTaskNegativeValue.Load()
+	this.multiTaskNegativeValue.Store(negative)
+	value := 1 + rand.Intn(1000000)
+	if negative {
+		value = -value
+	}
+
+	serviceId, err := this.getSensorServiceId(token, info)
+	if err != nil {
+		return
+	}
+	conn, err := this.connect(hubId)
+	if err != nil {
+		return
+	}
+	value2 := rand.Int()
+	_, err = this.publish(info, conn, value, value2)
+	this.disconnect(conn)
+	if err != nil {
+		return
+	}
+	_, err = this.waitForLastValues(token, info, serviceId, value, value2)
+	if err != nil {
+		this.metrics.DeviceDataIngestionTimeoutErr.Inc()
+		log.Println("ERROR: DeviceDataIngestionTimeoutErr: multi task process not started:", err)
+		return
+	}
+
+	err = this.process.MultiTaskProcessStartup(token, info, float64(value))
+	if err != nil {
+		return
+	}
+	this.process.MultiTaskProcessTeardown(token)
+}
@@ -0,0 +1,172 @@
+/*
+ * Copyright (c) 2023 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package canary
+
+import (
+	"errors"
+	"fmt"
+	"github.com/SENERGY-Platform/device-repository/lib/client"
+	"log"
+	"time"
+)
+
+// CheckOntology logs in and resolves all configured ontology ids.
+// failures are counted by the login and ontology metrics; a failed check is repeated by the next canary test run, which is skipped until the check succeeds.
+func (this *Canary) CheckOntology() error {
+	token, refresh, err := this.login()
+	if err != nil {
+		return err
+	}
+	defer this.logout(token, refresh)
+	return this.checkOntologyIfDue(token)
+}
+
+// checkOntologyIfDue repeats the ontology check after OntologyCheckInterval; failed checks are repeated on every call
+func (this *Canary) checkOntologyIfDue(token string) error {
+	this.ontologyCheckMux.Lock()
+	defer this.ontologyCheckMux.Unlock()
+	if this.ontologyCheckErr == nil && time.Since(this.lastOntologyCheck) < this.ontologyCheckInterval {
+		return nil
+	}
+	this.ontologyCheckErr = this.checkOntology(token)
+	this.lastOntologyCheck = time.Now()
+	return this.ontologyCheckErr
+}
+
+func (this *Canary) checkOntology(token string) (err error) {
+	this.metrics.OntologyCheckCount.Inc()
+	defer func() {
+		if err != nil {
+			this.metrics.OntologyCheckErr.Inc()
+			log.Println("ERROR: configured ontology ids do not match the platform; refuse to run canary tests:\n" + err.Error())
+		}
+	}()
+	errs := []error{}
+	check := func(configName string, checkErr error) {
+		if checkErr != nil {
+			errs = append(errs, fmt.Errorf("%v: %w", configName, checkErr))
+		}
+	}
+
+	check("canary_device_class_id", this.checkOntologyDeviceClass(this.config.CanaryDeviceClassId))
+	check("canary_process_characteristic_id", this.checkOntologyCharacteristic(this.config.CanaryProcessCharacteristicId, ""))
+
+	check("canary_cmd_function_id", this.checkOntologyFunction(this.config.CanaryCmdFunctionId, client.SES_ONTOLOGY_CONTROLLING_FUNCTION))
+	check("canary_cmd_characteristic_id", this.checkOntologyCharacteristic(this.config.CanaryCmdCharacteristicId, this.config.CanaryCmdValueType))
+	check("canary_cmd_function_id_2", this.checkOntologyFunction(this.config.CanaryCmdFunctionId2, client.SES_ONTOLOGY_CONTROLLING_FUNCTION))
+	check("canary_cmd_characteristic_id_2", this.checkOntologyCharacteristic(this.config.CanaryCmdCharacteristicId2, this.config.CanaryCmdValueType2))
+
+	check("canary_sensor_function_id", this.checkOntologyFunction(this.config.CanarySensorFunctionId, client.SES_ONTOLOGY_MEASURING_FUNCTION))
+	check("canary_sensor_characteristic_id", this.checkOntologyCharacteristic(this.config.CanarySensorCharacteristicId, this.config.CanarySensorValueType))
+	check("canary_sensor_aspect_id", this.checkOntologyAspect(this.config.CanarySensorAspectId))
+	check("canary_sensor_function_id_2", this.checkOntologyFunction(this.config.CanarySensorFunctionId2, client.SES_ONTOLOGY_MEASURING_FUNCTION))
+	check("canary_sensor_characteristic_id_2", this.checkOntologyCharacteristic(this.config.CanarySensorCharacteristicId2, this.config.CanarySensorValueType2))
+	check("canary_sensor_aspect_id_2", this.checkOntologyAspect(this.config.CanarySensorAspectId2))
+
+	if this.config.CanarySensorKelvinCharacteristicId != "" {
+		check("canary_sensor_kelvin_characteristic_id", this.checkOntologyCharacteristic(this.config.CanarySensorKelvinCharacteristicId, ""))
+	}
+	if this.config.CanarySensorFahrenheitCharacteristicId != "" {
+		check("canary_sensor_fahrenheit_characteristic_id", this.checkOntologyCharacteristic(this.config.CanarySensorFahrenheitCharacteristicId, ""))
+	}
+
+	check("canary_protocol_id", this.checkOntologyProtocol(token))
+
+	return errors.Join(errs...)
+}
+
+func (this *Canary) checkOntologyDeviceClass(id string) error {
+	this.metrics.DeviceRepoRequestCount.Inc()
+	start := time.Now()
+	_, err, _ := this.devicerepo.GetDeviceClass(id)
+	this.metrics.DeviceRepoRequestLatencyMs.Set(float64(time.Since(start).Milliseconds()))
+	if err != nil {
+		return fmt.Errorf("unable to read device-class %v: %w", id, err)
+	}
+	return nil
+}
+
+func (this *Canary) checkOntologyFunction(id string, expectedRdfType string) error {
+	this.metrics.DeviceRepoRequestCount.Inc()
+	start := time.Now()
+	f, err, _ := this.devicerepo.GetFunction(id)
+	this.metrics.DeviceRepoRequestLatencyMs.Set(float64(time.Since(start).Milliseconds()))
+	if err != nil {
+		return fmt.Errorf("unable to read function %v: %w", id, err)
+	}
+	if f.RdfType != expectedRdfType {
+		return fmt.Errorf("function %v (%v) has rdf_type %v; expected %v", id, f.Name, f.RdfType, expectedRdfType)
+	}
+	return nil
+}
+
+// checkOntologyCharacteristic checks the characteristic type, if expectedType is not empty
+func (this *Canary) checkOntologyCharacteristic(id string, expectedType string) error {
+	this.metrics.DeviceRepoRequestCount.Inc()
+	start := time.Now()
+	c, err, _ := this.devicerepo.GetCharacteristic(id)
+	this.metrics.DeviceRepoRequestLatencyMs.Set(float64(time.Since(start).Milliseconds()))
+	if err != nil {
+		return fmt.Errorf("unable to read characteristic %v: %w", id, err)
+	}
+	if expectedType != "" && string(c.Type) != expectedType {
+		return fmt.Errorf("characteristic %v (%v) has type %v; expected %v", id, c.Name, c.Type, expectedType)
+	}
+	return nil
+}
+
+func (this *Canary) checkOntologyAspect(id string) error {
+	this.metrics.DeviceRepoRequestCount.Inc()
+	start := time.Now()
+	_, err, _ := this.devicerepo.GetAspect(id)
+	this.metrics.DeviceRepoRequestLatencyMs.Set(float64(time.Since(start).Milliseconds()))
+	if err != nil {
+		return fmt.Errorf("unable to read aspect %v: %w", id, err)
+	}
+	return nil
+}
+
+// checkOntologyProtocol expects the configured protocol segment ids with the configured segment names
+func (this *Canary) checkOntologyProtocol(token string) error {
+	this.metrics.DeviceRepoRequestCount.Inc()
+	start := time.Now()
+	protocol, err, _ := this.devicerepo.ReadProtocol(this.config.CanaryProtocolId, token)
+	this.metrics.DeviceRepoRequestLatencyMs.Set(float64(time.Since(start).Milliseconds()))
+	if err != nil {
+		return fmt.Errorf("unable to read protocol %v: %w", this.config.CanaryProtocolId, err)
+	}
+	expectedSegments := map[string]string{
+		this.config.CanaryProtocolSegmentId:  this.config.CanaryProtocolSegmentName,
+		this.config.CanaryProtocolSegmentId2: this.config.CanaryProtocolSegmentName2,
+	}
+	errs := []error{}
+	for id, name := range expectedSegments {
+		found := false
+		for _, segment := range protocol.ProtocolSegments {
+			if segment.Id == id {
+				found = true
+				if segment.Name != name {
+					errs = append(errs, fmt.Errorf("protocol segment %v has name %v; expected %v", id, segment.Name, name))
+				}
+			}
+		}
+		if !found {
+			errs = append(errs, fmt.Errorf("protocol %v (%v) has no segment %v", protocol.Id, protocol.Name, id))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -17,6 +17,7 @@
 package canary
 
 import (
+	"github.com/SENERGY-Platform/snowflake-canary/pkg/events"
 	"log"
 	"math/rand"
 	"sync"
@@ -41,17 +42,34 @@ func (this *Canary) StartTests() {
 		}
 		defer this.logout(token, refresh)
 
+		err = this.checkOntologyIfDue(token)
+		if err != nil {
+			return
+		}
+
 		deviceInfo, err := this.devicemeta.EnsureDevice(token)
 		if err != nil {
 			return
 		}
 
+		this.devicemeta.CheckDeviceTypeDrift(token, deviceInfo.DeviceTypeId)
+
 		this.testDeviceConnection(wg, token, deviceInfo)
 
 		this.testMetadata(wg, token, deviceInfo)
 
+		this.testValidation(wg, token, deviceInfo)
+
+		this.testHubLifecycle(wg, token, deviceInfo)
+
+		this.testDeviceLifecycle(wg, token, deviceInfo)
+
+		this.testDeviceTypeUpdate(wg, token)
+
 		wg.Wait()
 
+		this.cleanup(token, deviceInfo)
+
 	}()
 }
 
@@ -69,6 +87,8 @@ func (this *Canary) testDeviceConnection(wg *sync.WaitGroup, token string, info
 			return
 		}
 
+		groupId, groupErr := this.ensureDeviceGroup(token, info)
+
 		conn, err := this.connect(hubId)
 		if err != nil {
 			return
@@ -76,23 +96,77 @@ func (this *Canary) testDeviceConnection(wg *sync.WaitGroup, token string, info
 
 		this.subscribe(info, conn)
 
-		value1 := rand.Int()
-		value2 := rand.Int()
+		this.sampleTimes.reset()
 
-		this.publish(info, conn, value1, value2)
+		//negative path of the event process; checked after the process startups, before value1 is expected to start exactly one instance
+		nonMatchingValue := events.NonMatchingValue()
+		nonMatchingErr := eventDeplErr
+		nonMatchingPublishedAt := time.Time{}
+		if eventDeplErr == nil {
+			nonMatchingPublishedAt, nonMatchingErr = this.publish(info, conn, nonMatchingValue, rand.Int())
+		}
 
 		processErr := this.process.ProcessStartup(token, info)
 
+		groupProcessErr := groupErr
+		if groupErr == nil {
+			groupProcessErr = this.process.GroupProcessStartup(token, groupId)
+		}
+
+		conversionProcessErr := this.process.ConversionProcessStartup(token, info)
+
+		incidentProcessErr := this.process.IncidentProcessStartup(token, info)
+
+		timerProcessErr := this.process.TimerProcessStartup(token, info)
+
+		startParameterProcessErr := this.process.StartParameterProcessStartup(token, info)
+
+		if nonMatchingErr == nil {
+			this.events.CheckNoInstanceStarted(token, nonMatchingValue, nonMatchingPublishedAt)
+		}
+
+		value1 := rand.Int()
+		value2 := rand.Int()
+
+		publishedAt, err := this.publish(info, conn, value1, value2)
+		if err == nil {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				this.measureIngestionLatency(token, info, publishedAt, value1, value2)
+			}()
+		}
+
 		time.Sleep(this.getChangeGuaranteeDuration())
 
 		this.checkDeviceConnState(token, info, true)
 
-		this.checkDeviceValue(token, info, value1, value2)
+		this.checkDeviceValue(token, info, value1, value2, publishedAt)
 
 		if processErr == nil {
 			this.process.ProcessTeardown(token)
 		}
 
+		if groupProcessErr == nil {
+			this.process.GroupProcessTeardown(token)
+		}
+
+		if conversionProcessErr == nil {
+			this.process.ConversionProcessTeardown(token)
+		}
+
+		if incidentProcessErr == nil {
+			this.process.IncidentProcessTeardown(token)
+		}
+
+		if timerProcessErr == nil {
+			this.process.TimerProcessTeardown(token)
+		}
+
+		if startParameterProcessErr == nil {
+			this.process.StartParameterProcessTeardown(token)
+		}
+
 		this.disconnect(conn)
 
 		time.Sleep(this.getChangeGuaranteeDuration())
@@ -100,8 +174,29 @@ func (this *Canary) testDeviceConnection(wg *sync.WaitGroup, token string, info
 		this.checkDeviceConnState(token, info, false)
 
 		if eventDeplErr == nil {
-			this.events.ProcessTeardown(token)
+			this.events.ProcessTeardown(token, value1, publishedAt)
 		}
 
+		//the following checks publish additional values to the canary device.
+		//they run after the event process teardown and one after another, so that the values affect neither the event process nor each other.
+		this.testMultiTaskProcess(token, info, hubId)
+
+		this.testHistory(token, info, hubId)
+
+		this.testMessageLoss(token, info, hubId)
+
 	}()
 }
+
+// cleanup removes leaked canary entities; the device and hub used by the canary are kept
+func (this *Canary) cleanup(token string, info DeviceInfo) {
+	hubs, err := this.listCanaryHubs(token)
+	if err != nil {
+		return
+	}
+	hubId := ""
+	if len(hubs) > 0 {
+		hubId = hubs[0].Id
+	}
+	this.janitor.Cleanup(token, info, hubId)
+}
@@ -31,6 +31,23 @@ type Config struct {
 
 	GuaranteeChangeAfter string `json:"guarantee_change_after"`
 
+	IngestionLatencyTimeout      string `json:"ingestion_latency_timeout"`
+	IngestionLatencyPollInterval string `json:"ingestion_latency_poll_interval"`
+	LastValueTimeTolerance       string `json:"last_value_time_tolerance"`
+
+	DeviceTypeUpdatePropagationTimeout string `json:"device_type_update_propagation_timeout"`
+	DeviceTypeUpdatePollInterval       string `json:"device_type_update_poll_interval"`
+
+	ProcessInstanceTimeout      string `json:"process_instance_timeout"`
+	ProcessInstancePollInterval string `json:"process_instance_poll_interval"`
+
+	TimerProcessDuration  string `json:"timer_process_duration"`
+	TimerProcessTolerance string `json:"timer_process_tolerance"`
+
+	EventNegativeWindow string `json:"event_negative_window"`
+
+	MessageLossBurstSize int `json:"message_loss_burst_size"`
+
 	AuthEndpoint string `json:"auth_endpoint"`
 	AuthClientId string `json:"auth_client_id" config:"secret"`
 	AuthUsername string `json:"auth_username" config:"secret"`
@@ -40,12 +57,16 @@ type Config struct {
 	DeviceRepositoryUrl     string `json:"device_repository_url"`
 	ConnectorMqttBrokerUrl  string `json:"connector_mqtt_broker_url"`
 	LastValueQueryUrl       string `json:"last_value_query_url"`
+	TimescaleQueryUrl       string `json:"timescale_query_url"`
 	NotificationUrl         string `json:"notification_url"`
 	ProcessDeploymentUrl    string `json:"process_deployment_url"`
 	ProcessEngineWrapperUrl string `json:"process_engine_wrapper_url"`
+	ProcessIncidentApiUrl   string `json:"process_incident_api_url"`
 
 	CanaryDeviceClassId string `json:"canary_device_class_id"`
 
+	CanaryProcessCharacteristicId string `json:"canary_process_characteristic_id"`
+
 	CanaryCmdFunctionId       string `json:"canary_cmd_function_id"`
 	CanaryCmdCharacteristicId string `json:"canary_cmd_characteristic_id"`
 	CanaryCmdValueType        string `json:"canary_cmd_value_type"`
@@ -60,6 +81,11 @@ type Config struct {
 	CanarySensorValueType        string `json:"canary_sensor_value_type"`
 	CanarySensorAspectId         string `json:"canary_sensor_aspect_id"`
 
+	CanarySensorKelvinCharacteristicId     string  `json:"canary_sensor_kelvin_characteristic_id"`
+	CanarySensorKelvinTolerance            float64 `json:"canary_sensor_kelvin_tolerance"`
+	CanarySensorFahrenheitCharacteristicId string  `json:"canary_sensor_fahrenheit_characteristic_id"`
+	CanarySensorFahrenheitTolerance        float64 `json:"canary_sensor_fahrenheit_tolerance"`
+
 	CanarySensorFunctionId2       string `json:"canary_sensor_function_id_2"`
 	CanarySensorCharacteristicId2 string `json:"canary_sensor_characteristic_id_2"`
 	CanarySensorValueType2        string `json:"canary_sensor_value_type_2"`
@@ -73,6 +99,12 @@ type Config struct {
 
 	CanaryHubName string `json:"canary_hub_name"`
 
+	UpdateDriftedDeviceType bool `json:"update_drifted_device_type"`
+
+	JanitorMaxAge string `json:"janitor_max_age"`
+
+	OntologyCheckInterval string `json:"ontology_check_interval"`
+
 	TopicsWithOwner bool `json:"topics_with_owner"`
 }
 
@@ -0,0 +1,86 @@
+/*
+ * Copyright (c) 2023 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package devicemetadata
+
+import (
+	"errors"
+	"github.com/SENERGY-Platform/snowflake-canary/pkg/configuration"
+	"slices"
+)
+
+// exactTolerance is used for characteristics without conversion, which only differ in the value type
+const exactTolerance = 1e-9
+
+// CelsiusCharacteristicId is the Degree Celsius (float) characteristic of the platform
+const CelsiusCharacteristicId = "urn:infai:ses:characteristic:5ba31623-0ccb-4488-bfb7-f73b50e03b5a"
+
+// getCelsiusCharacteristicIds returns the known Degree Celsius characteristics, which differ from the sensor value at most in the value type
+func getCelsiusCharacteristicIds(config configuration.Config) []string {
+	return []string{CelsiusCharacteristicId, config.CanarySensorCharacteristicId, config.CanaryCmdCharacteristicId}
+}
+
+type CharacteristicConversion struct {
+	Name             string
+	CharacteristicId string
+	Tolerance        float64 //relative to the expected value
+	Convert          func(celsius float64) float64
+}
+
+// GetSensorConversions returns the configured conversions of the Degree Celsius sensor value
+func GetSensorConversions(config configuration.Config) (result []CharacteristicConversion) {
+	if config.CanarySensorKelvinCharacteristicId != "" {
+		result = append(result, CharacteristicConversion{
+			Name:             "kelvin",
+			CharacteristicId: config.CanarySensorKelvinCharacteristicId,
+			Tolerance:        config.CanarySensorKelvinTolerance,
+			Convert: func(celsius float64) float64 {
+				return celsius + 273.15
+			},
+		})
+	}
+	if config.CanarySensorFahrenheitCharacteristicId != "" {
+		result = append(result, CharacteristicConversion{
+			Name:             "fahrenheit",
+			CharacteristicId: config.CanarySensorFahrenheitCharacteristicId,
+			Tolerance:        config.CanarySensorFahrenheitTolerance,
+			Convert: func(celsius float64) float64 {
+				return celsius*9/5 + 32
+			},
+		})
+	}
+	return result
+}
+
+// GetSensorConversion returns the conversion of the Degree Celsius sensor value to characteristicId
+func GetSensorConversion(config configuration.Config, characteristicId string) (result CharacteristicConversion, err error) {
+	if slices.Contains(getCelsiusCharacteristicIds(config), characteristicId) {
+		return CharacteristicConversion{
+			Name:             "celsius",
+			CharacteristicId: characteristicId,
+			Tolerance:        exactTolerance,
+			Convert: func(celsius float64) float64 {
+				return celsius
+			},
+		}, nil
+	}
+	for _, conversion := range GetSensorConversions(config) {
+		if conversion.CharacteristicId == characteristicId {
+			return conversion, nil
+		}
+	}
+	return result, errors.New("no known conversion from canary_sensor_characteristic_id to " + characteristicId)
+}
@@ -0,0 +1,84 @@
+/*
+ * Copyright (c) 2023 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package devicemetadata
+
+import (
+	"github.com/SENERGY-Platform/models/go/models"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AttributeCreatedAt stores the RFC 3339 creation time of canary devices, device-types and device-groups,
+// because the platform does not provide one. the janitor uses it to find leaked entities.
+const AttributeCreatedAt = "senergy/snowflake-canary-created-at"
+
+// WithCreatedAt returns the attributes with the creation time of existing, or the current time if existing has none
+func WithCreatedAt(attributes []models.Attribute, existing []models.Attribute) []models.Attribute {
+	createdAt := time.Now().UTC().Format(time.RFC3339)
+	if i := slices.IndexFunc(existing, isCreatedAtAttribute); i >= 0 {
+		createdAt = existing[i].Value
+	}
+	result := slices.DeleteFunc(slices.Clone(attributes), isCreatedAtAttribute)
+	return append(result, models.Attribute{Key: AttributeCreatedAt, Value: createdAt, Origin: "canary"})
+}
+
+// CreatedAt returns false if the attributes contain no valid creation time
+func CreatedAt(attributes []models.Attribute) (time.Time, bool) {
+	i := slices.IndexFunc(attributes, isCreatedAtAttribute)
+	if i < 0 {
+		return time.Time{}, false
+	}
+	result, err := time.Parse(time.RFC3339, attributes[i].Value)
+	return result, err == nil
+}
+
+func isCreatedAtAttribute(attr models.Attribute) bool {
+	return attr.Key == AttributeCreatedAt
+}
+
+// purposes of temporary hubs; the janitor only deletes temporary hubs with one of them
+const (
+	TemporaryHubLifecycle        = "lifecycle"
+	TemporaryHubDeviceLifecycle  = "device-lifecycle"
+	TemporaryHubDeviceTypeUpdate = "device-type-update"
+)
+
+var temporaryHubPurposes = []string{TemporaryHubLifecycle, TemporaryHubDeviceLifecycle, TemporaryHubDeviceTypeUpdate}
+
+// TemporaryHubName returns "<canaryHubName>-<purpose>-<unix time>".
+// the creation time is part of the name, because hubs have neither attributes nor a platform creation time.
+func TemporaryHubName(canaryHubName string, purpose string) string {
+	return canaryHubName + "-" + purpose + "-" + strconv.FormatInt(time.Now().Unix(), 10)
+}
+
+// hubCreatedAt returns false if the name was not created by TemporaryHubName for canaryHubName
+func hubCreatedAt(canaryHubName string, name string) (time.Time, bool) {
+	for _, purpose := range temporaryHubPurposes {
+		suffix, found := strings.CutPrefix(name, canaryHubName+"-"+purpose+"-")
+		if !found || suffix == "" || strings.Trim(suffix, "0123456789") != "" {
+			continue
+		}
+		unix, err := strconv.ParseInt(suffix, 10, 64)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return time.Unix(unix, 0), true
+	}
+	return time.Time{}, false
+}
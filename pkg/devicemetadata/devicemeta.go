@@ -29,6 +29,7 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"runtime/debug"
 	"time"
 )
@@ -38,10 +39,12 @@ type DeviceMetaData struct {
 	metrics              *metrics.Metrics
 	config               configuration.Config
 	guaranteeChangeAfter time.Duration
+	updateTimeout        time.Duration
+	updatePollInterval   time.Duration
 }
 
-func NewDeviceMetaData(devicerepo devicerepo.Interface, metrics *metrics.Metrics, config configuration.Config, guaranteeChangeAfter time.Duration) *DeviceMetaData {
-	return &DeviceMetaData{devicerepo: devicerepo, metrics: metrics, config: config, guaranteeChangeAfter: guaranteeChangeAfter}
+func NewDeviceMetaData(devicerepo devicerepo.Interface, metrics *metrics.Metrics, config configuration.Config, guaranteeChangeAfter time.Duration, updateTimeout time.Duration, updatePollInterval time.Duration) *DeviceMetaData {
+	return &DeviceMetaData{devicerepo: devicerepo, metrics: metrics, config: config, guaranteeChangeAfter: guaranteeChangeAfter, updateTimeout: updateTimeout, updatePollInterval: updatePollInterval}
 }
 
 func (this *DeviceMetaData) getChangeGuaranteeDuration() time.Duration {
@@ -87,14 +90,20 @@ func (this *DeviceMetaData) CreateCanaryDevice(token string) (device DeviceInfo,
 		}},
 		DeviceTypeId: dt.Id,
 	}
+	device, err = this.CreateDevice(token, device)
+	time.Sleep(this.getChangeGuaranteeDuration())
+	return device, err
+}
 
+func (this *DeviceMetaData) CreateDevice(token string, device DeviceInfo) (result DeviceInfo, err error) {
+	device.Attributes = WithCreatedAt(device.Attributes, nil)
 	buf := &bytes.Buffer{}
 	err = json.NewEncoder(buf).Encode(device)
 	if err != nil {
 		this.metrics.UncategorizedErr.Inc()
 		log.Println("ERROR:", err)
 		debug.PrintStack()
-		return device, err
+		return result, err
 	}
 	this.metrics.DeviceMetaUpdateCount.Inc()
 	req, err := http.NewRequest(http.MethodPost, this.config.DeviceManagerUrl+"/devices?wait=true", buf)
@@ -102,19 +111,39 @@ func (this *DeviceMetaData) CreateCanaryDevice(token string) (device DeviceInfo,
 		this.metrics.UncategorizedErr.Inc()
 		log.Println("ERROR:", err)
 		debug.PrintStack()
-		return device, err
+		return result, err
 	}
 	req.Header.Set("Authorization", token)
 	start := time.Now()
-	device, _, err = Do[DeviceInfo](req)
+	result, _, err = Do[DeviceInfo](req)
 	this.metrics.DeviceMetaUpdateLatencyMs.Set(float64(time.Since(start).Milliseconds()))
 	if err != nil {
 		this.metrics.DeviceMetaUpdateErr.Inc()
 		log.Println("ERROR:", err)
 		debug.PrintStack()
 	}
-	time.Sleep(this.getChangeGuaranteeDuration())
-	return device, err
+	return result, err
+}
+
+func (this *DeviceMetaData) DeleteDevice(token string, id string) (err error) {
+	this.metrics.DeviceMetaUpdateCount.Inc()
+	req, err := http.NewRequest(http.MethodDelete, this.config.DeviceManagerUrl+"/devices/"+url.PathEscape(id)+"?wait=true", nil)
+	if err != nil {
+		this.metrics.UncategorizedErr.Inc()
+		log.Println("ERROR:", err)
+		debug.PrintStack()
+		return err
+	}
+	req.Header.Set("Authorization", token)
+	start := time.Now()
+	_, err = DoWithoutResult(req)
+	this.metrics.DeviceMetaUpdateLatencyMs.Set(float64(time.Since(start).Milliseconds()))
+	if err != nil {
+		this.metrics.DeviceMetaUpdateErr.Inc()
+		log.Println("ERROR:", err)
+		debug.PrintStack()
+	}
+	return err
 }
 
 func (this *DeviceMetaData) EnsureDeviceType(token string) (result DeviceTypeInfo, err error) {
@@ -157,8 +186,9 @@ func (this *DeviceMetaData) ListCanaryDeviceTypes(token string) (result []Device
 	return result, err
 }
 
-func (this *DeviceMetaData) CreateCanaryDeviceType(token string) (deviceType DeviceTypeInfo, err error) {
-	dt := models.DeviceType{
+// GetCanaryDeviceTypeSpec returns the device type expected by the canary, as defined by the configuration
+func (this *DeviceMetaData) GetCanaryDeviceTypeSpec() models.DeviceType {
+	return models.DeviceType{
 		Name:          "snowflake-canary-device-type",
 		Description:   "used for canary service github.com/SENERGY-Platform/snowflake-canary",
 		DeviceClassId: this.config.CanaryDeviceClassId,
@@ -217,6 +247,11 @@ func (this *DeviceMetaData) CreateCanaryDeviceType(token string) (deviceType Dev
 				Description: "canary sensor service, needed to test device data handling",
 				Interaction: models.EVENT_AND_REQUEST,
 				ProtocolId:  this.config.CanaryProtocolId,
+				Attributes: []models.Attribute{{
+					Key:    TimePathAttributeKey,
+					Value:  SensorTimePath,
+					Origin: "canary",
+				}},
 				Outputs: []models.Content{
 					{
 						ContentVariable: models.ContentVariable{
@@ -235,6 +270,11 @@ func (this *DeviceMetaData) CreateCanaryDeviceType(token string) (deviceType Dev
 											AspectId:             this.config.CanarySensorAspectId,
 											SerializationOptions: []string{models.SerializationOptionXmlAttribute},
 										},
+										{
+											Name:                 "time",
+											Type:                 models.String,
+											SerializationOptions: []string{models.SerializationOptionXmlAttribute},
+										},
 									},
 								},
 							},
@@ -257,6 +297,10 @@ func (this *DeviceMetaData) CreateCanaryDeviceType(token string) (deviceType Dev
 			},
 		},
 	}
+}
+
+func (this *DeviceMetaData) CreateCanaryDeviceType(token string) (deviceType DeviceTypeInfo, err error) {
+	dt := this.GetCanaryDeviceTypeSpec()
 
 	buf := &bytes.Buffer{}
 	err = json.NewEncoder(buf).Encode(dt)
@@ -301,3 +345,16 @@ func Do[T any](req *http.Request) (result T, code int, err error) {
 	}
 	return result, resp.StatusCode, nil
 }
+
+func DoWithoutResult(req *http.Request) (code int, err error) {
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	defer resp.Body.Close()
+	temp, _ := io.ReadAll(resp.Body) //read error response end ensure that resp.Body is read to EOF
+	if resp.StatusCode > 299 {
+		return resp.StatusCode, errors.New(string(temp))
+	}
+	return resp.StatusCode, nil
+}
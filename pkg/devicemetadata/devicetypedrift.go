@@ -0,0 +1,302 @@
+/*
+ * Copyright (c) 2023 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package devicemetadata
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/SENERGY-Platform/models/go/models"
+	"log"
+	"net/http"
+	"net/url"
+	"reflect"
+	"runtime/debug"
+	"time"
+)
+
+// CheckDeviceTypeDrift compares the existing canary device type with GetCanaryDeviceTypeSpec.
+// a device type created by an older canary version or with an older config.json may differ in functions, characteristics or segments.
+// if config.UpdateDriftedDeviceType is set, the device type is updated and the update is verified.
+// a missing sensor time path is always migrated, see migrateSensorTimePath.
+func (this *DeviceMetaData) CheckDeviceTypeDrift(token string, deviceTypeId string) {
+	dt, err := this.readDeviceType(token, deviceTypeId)
+	if err != nil {
+		return
+	}
+	dt, err = this.migrateSensorTimePath(token, dt)
+	if err != nil {
+		return
+	}
+	expected := this.GetCanaryDeviceTypeSpec()
+	diff := DeviceTypeDiff(expected, dt)
+	this.metrics.DeviceTypeDrift.Set(float64(len(diff)))
+	if len(diff) == 0 {
+		return
+	}
+	log.Printf("WARNING: canary device type %v differs from spec:\n%v\n", deviceTypeId, diff)
+	if !this.config.UpdateDriftedDeviceType {
+		return
+	}
+
+	err = this.UpdateDeviceType(token, withIdsOf(expected, dt))
+	if err != nil {
+		return
+	}
+
+	diff, err = this.waitForDeviceTypeSpec(token, deviceTypeId, expected)
+	if err != nil {
+		return
+	}
+	this.metrics.DeviceTypeDrift.Set(float64(len(diff)))
+	if len(diff) > 0 {
+		this.metrics.UnexpectedDeviceTypeUpdatePropagationErr.Inc()
+		log.Printf("UnexpectedDeviceTypeUpdatePropagationErr: device type %v still differs from spec after update:\n%v\n", deviceTypeId, diff)
+	}
+}
+
+func (this *DeviceMetaData) readDeviceType(token string, id string) (dt models.DeviceType, err error) {
+	this.metrics.DeviceRepoRequestCount.Inc()
+	start := time.Now()
+	dt, err, _ = this.devicerepo.ReadDeviceType(id, token)
+	this.metrics.DeviceRepoRequestLatencyMs.Set(float64(time.Since(start).Milliseconds()))
+	if err != nil {
+		this.metrics.DeviceRepoRequestErr.Inc()
+		log.Println("ERROR:", err)
+		debug.PrintStack()
+	}
+	return dt, err
+}
+
+// waitForDeviceTypeSpec polls the device type until it matches expected or device_type_update_propagation_timeout is reached.
+// the differences of the last read are returned.
+func (this *DeviceMetaData) waitForDeviceTypeSpec(token string, id string, expected models.DeviceType) (diff []string, err error) {
+	deadline := time.Now().Add(this.updateTimeout)
+	for {
+		dt, err := this.readDeviceType(token, id)
+		if err != nil {
+			return diff, err
+		}
+		diff = DeviceTypeDiff(expected, dt)
+		if len(diff) == 0 || time.Now().After(deadline) {
+			return diff, nil
+		}
+		time.Sleep(this.updatePollInterval)
+	}
+}
+
+func (this *DeviceMetaData) UpdateDeviceType(token string, dt models.DeviceType) (err error) {
+	buf := &bytes.Buffer{}
+	err = json.NewEncoder(buf).Encode(dt)
+	if err != nil {
+		return err
+	}
+	this.metrics.DeviceMetaUpdateCount.Inc()
+	req, err := http.NewRequest(http.MethodPut, this.config.DeviceManagerUrl+"/device-types/"+url.PathEscape(dt.Id)+"?wait=true", buf)
+	if err != nil {
+		this.metrics.UncategorizedErr.Inc()
+		log.Println("ERROR:", err)
+		debug.PrintStack()
+		return err
+	}
+	req.Header.Set("Authorization", token)
+	start := time.Now()
+	_, _, err = Do[models.DeviceType](req)
+	this.metrics.DeviceMetaUpdateLatencyMs.Set(float64(time.Since(start).Milliseconds()))
+	if err != nil {
+		this.metrics.DeviceMetaUpdateErr.Inc()
+		log.Println("ERROR:", err)
+		debug.PrintStack()
+	}
+	return err
+}
+
+// withIdsOf returns the expected device type with the ids of the existing device type,
+// so that references to the existing services, contents and content variables stay valid.
+// services are matched by local id, contents by protocol segment and content variables by name.
+// the creation time attribute of the existing device type is kept for the janitor.
+func withIdsOf(expected models.DeviceType, existing models.DeviceType) models.DeviceType {
+	expected.Id = existing.Id
+	expected.Attributes = WithCreatedAt(expected.Attributes, existing.Attributes)
+	services := []models.Service{}
+	for _, service := range expected.Services {
+		for _, existingService := range existing.Services {
+			if existingService.LocalId == service.LocalId {
+				service.Id = existingService.Id
+				service.Inputs = contentsWithIdsOf(service.Inputs, existingService.Inputs)
+				service.Outputs = contentsWithIdsOf(service.Outputs, existingService.Outputs)
+			}
+		}
+		services = append(services, service)
+	}
+	expected.Services = services
+	return expected
+}
+
+func contentsWithIdsOf(expected []models.Content, existing []models.Content) []models.Content {
+	if expected == nil {
+		return nil
+	}
+	result := []models.Content{}
+	for _, content := range expected {
+		for _, existingContent := range existing {
+			if existingContent.ProtocolSegmentId == content.ProtocolSegmentId {
+				content.Id = existingContent.Id
+				content.ContentVariable = contentVariableWithIdsOf(content.ContentVariable, existingContent.ContentVariable)
+			}
+		}
+		result = append(result, content)
+	}
+	return result
+}
+
+func contentVariableWithIdsOf(expected models.ContentVariable, existing models.ContentVariable) models.ContentVariable {
+	if expected.Name != existing.Name {
+		return expected
+	}
+	expected.Id = existing.Id
+	if expected.SubContentVariables == nil {
+		return expected
+	}
+	subs := []models.ContentVariable{}
+	for _, sub := range expected.SubContentVariables {
+		for _, existingSub := range existing.SubContentVariables {
+			if existingSub.Name == sub.Name {
+				sub = contentVariableWithIdsOf(sub, existingSub)
+			}
+		}
+		subs = append(subs, sub)
+	}
+	expected.SubContentVariables = subs
+	return expected
+}
+
+// DeviceTypeDiff lists the differences between the expected and the actual device type.
+// generated ids are ignored; services are matched by local id, contents by protocol segment.
+func DeviceTypeDiff(expected models.DeviceType, actual models.DeviceType) (diff []string) {
+	if expected.DeviceClassId != actual.DeviceClassId {
+		diff = append(diff, fmt.Sprintf("device_class_id: %v != %v", actual.DeviceClassId, expected.DeviceClassId))
+	}
+	if len(expected.Services) != len(actual.Services) {
+		diff = append(diff, fmt.Sprintf("service count: %v != %v", len(actual.Services), len(expected.Services)))
+	}
+	for _, expectedService := range expected.Services {
+		found := false
+		for _, actualService := range actual.Services {
+			if actualService.LocalId == expectedService.LocalId {
+				found = true
+				diff = append(diff, serviceDiff(expectedService, actualService)...)
+			}
+		}
+		if !found {
+			diff = append(diff, fmt.Sprintf("missing service %v", expectedService.LocalId))
+		}
+	}
+	return diff
+}
+
+func serviceDiff(expected models.Service, actual models.Service) (diff []string) {
+	prefix := "services." + expected.LocalId
+	if expected.Name != actual.Name {
+		diff = append(diff, fmt.Sprintf("%v.name: %v != %v", prefix, actual.Name, expected.Name))
+	}
+	if expected.Interaction != actual.Interaction {
+		diff = append(diff, fmt.Sprintf("%v.interaction: %v != %v", prefix, actual.Interaction, expected.Interaction))
+	}
+	if expected.ProtocolId != actual.ProtocolId {
+		diff = append(diff, fmt.Sprintf("%v.protocol_id: %v != %v", prefix, actual.ProtocolId, expected.ProtocolId))
+	}
+	for _, attrDiff := range AttributeDiff(expected.Attributes, actual.Attributes) {
+		diff = append(diff, prefix+".attributes: "+attrDiff)
+	}
+	diff = append(diff, contentsDiff(prefix+".inputs", expected.Inputs, actual.Inputs)...)
+	diff = append(diff, contentsDiff(prefix+".outputs", expected.Outputs, actual.Outputs)...)
+	return diff
+}
+
+func contentsDiff(prefix string, expected []models.Content, actual []models.Content) (diff []string) {
+	if len(expected) != len(actual) {
+		diff = append(diff, fmt.Sprintf("%v count: %v != %v", prefix, len(actual), len(expected)))
+	}
+	for _, expectedContent := range expected {
+		found := false
+		for _, actualContent := range actual {
+			if actualContent.ProtocolSegmentId == expectedContent.ProtocolSegmentId {
+				found = true
+				segmentPrefix := prefix + "." + expectedContent.ProtocolSegmentId
+				if expectedContent.Serialization != actualContent.Serialization {
+					diff = append(diff, fmt.Sprintf("%v.serialization: %v != %v", segmentPrefix, actualContent.Serialization, expectedContent.Serialization))
+				}
+				diff = append(diff, contentVariableDiff(segmentPrefix, expectedContent.ContentVariable, actualContent.ContentVariable)...)
+			}
+		}
+		if !found {
+			diff = append(diff, fmt.Sprintf("%v: missing content for protocol segment %v", prefix, expectedContent.ProtocolSegmentId))
+		}
+	}
+	return diff
+}
+
+func contentVariableDiff(prefix string, expected models.ContentVariable, actual models.ContentVariable) (diff []string) {
+	prefix = prefix + "." + expected.Name
+	if expected.Name != actual.Name {
+		diff = append(diff, fmt.Sprintf("%v.name: %v != %v", prefix, actual.Name, expected.Name))
+	}
+	if expected.Type != actual.Type {
+		diff = append(diff, fmt.Sprintf("%v.type: %v != %v", prefix, actual.Type, expected.Type))
+	}
+	if expected.CharacteristicId != actual.CharacteristicId {
+		diff = append(diff, fmt.Sprintf("%v.characteristic_id: %v != %v", prefix, actual.CharacteristicId, expected.CharacteristicId))
+	}
+	if expected.FunctionId != actual.FunctionId {
+		diff = append(diff, fmt.Sprintf("%v.function_id: %v != %v", prefix, actual.FunctionId, expected.FunctionId))
+	}
+	if expected.AspectId != actual.AspectId {
+		diff = append(diff, fmt.Sprintf("%v.aspect_id: %v != %v", prefix, actual.AspectId, expected.AspectId))
+	}
+	if len(expected.SerializationOptions) != 0 || len(actual.SerializationOptions) != 0 {
+		if !reflect.DeepEqual(expected.SerializationOptions, actual.SerializationOptions) {
+			diff = append(diff, fmt.Sprintf("%v.serialization_options: %v != %v", prefix, actual.SerializationOptions, expected.SerializationOptions))
+		}
+	}
+	if !reflect.DeepEqual(JsonNormalize(expected.Value), JsonNormalize(actual.Value)) {
+		diff = append(diff, fmt.Sprintf("%v.value: %v != %v", prefix, actual.Value, expected.Value))
+	}
+	if len(expected.SubContentVariables) != len(actual.SubContentVariables) {
+		diff = append(diff, fmt.Sprintf("%v.sub_content_variables count: %v != %v", prefix, len(actual.SubContentVariables), len(expected.SubContentVariables)))
+	}
+	for _, expectedSub := range expected.SubContentVariables {
+		found := false
+		for _, actualSub := range actual.SubContentVariables {
+			if actualSub.Name == expectedSub.Name {
+				found = true
+				diff = append(diff, contentVariableDiff(prefix, expectedSub, actualSub)...)
+			}
+		}
+		if !found {
+			diff = append(diff, fmt.Sprintf("%v: missing sub content variable %v", prefix, expectedSub.Name))
+		}
+	}
+	return diff
+}
+
+// JsonNormalize converts in to the types encoding/json decodes into interface{}, to compare it with decoded values
+func JsonNormalize(in interface{}) (out interface{}) {
+	temp, _ := json.Marshal(in)
+	json.Unmarshal(temp, &out)
+	return
+}
@@ -0,0 +1,269 @@
+/*
+ * Copyright (c) 2023 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package devicemetadata
+
+import (
+	devicerepo "github.com/SENERGY-Platform/device-repository/lib/client"
+	"github.com/SENERGY-Platform/device-repository/lib/model"
+	"github.com/SENERGY-Platform/models/go/models"
+	"github.com/SENERGY-Platform/snowflake-canary/pkg/configuration"
+	"github.com/SENERGY-Platform/snowflake-canary/pkg/metrics"
+	"github.com/SENERGY-Platform/snowflake-canary/pkg/processplatform"
+	"github.com/prometheus/client_golang/prometheus"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// CanaryProcessDeploymentNames contains the deployment names used by the canary process checks
+var CanaryProcessDeploymentNames = []string{"snowflake_canary_process", "snowflake_canary_group_process", "snowflake_canary_conversion_process", "snowflake_canary_incident_process", "snowflake_canary_multi_task_process", "snowflake_canary_timer_process", "snowflake_canary_start_parameter_process", "snowflake_canary_event_process"}
+
+const janitorPageSize = 100
+
+// Janitor removes canary entities, leaked by crashed or concurrent canary runs.
+// process deployments are aged by their platform deployment time. devices, device-types and device-groups
+// are aged by the AttributeCreatedAt attribute, temporary hubs by the time in their TemporaryHubName.
+// devices, device-types and device-groups without creation time predate the attribute and count as old.
+// hubs are only deleted if they are named by TemporaryHubName or duplicate the canary hub name,
+// because other hubs matching the canary hub name search may belong to someone else.
+// the entities currently used by the canary are always kept.
+type Janitor struct {
+	devicerepo devicerepo.Interface
+	platform   *processplatform.Client
+	metrics    *metrics.Metrics
+	config     configuration.Config
+	maxAge     time.Duration
+}
+
+func NewJanitor(devicerepo devicerepo.Interface, platform *processplatform.Client, metrics *metrics.Metrics, config configuration.Config, maxAge time.Duration) *Janitor {
+	return &Janitor{devicerepo: devicerepo, platform: platform, metrics: metrics, config: config, maxAge: maxAge}
+}
+
+func (this *Janitor) Cleanup(token string, device DeviceInfo, hubId string) {
+	this.cleanupDevices(token, AttributeUsedForCanaryDevice, keepId(device.Id))
+	this.cleanupDevices(token, AttributeUsedForTemporaryCanaryDevice, keepNone)
+	this.cleanupDevices(token, AttributeUsedForSecondaryCanaryDevice, keepFirst)
+	this.cleanupDeviceTypes(token, AttributeUsedForCanaryDeviceType, keepId(device.DeviceTypeId))
+	this.cleanupDeviceTypes(token, AttributeUsedForSecondaryCanaryDeviceType, keepFirst)
+	this.cleanupDeviceGroups(token)
+	this.cleanupHubs(token, hubId)
+	this.cleanupProcessDeployments(token)
+}
+
+// keepFunc decides by the list position and the id, if an entity is in use.
+// entities, which the canary reuses without knowing their id, are the first of the list the canary reads them with.
+type keepFunc func(index int64, id string) bool
+
+func keepId(keep string) keepFunc {
+	return func(_ int64, id string) bool {
+		return id == keep
+	}
+}
+
+func keepFirst(index int64, _ string) bool {
+	return index == 0
+}
+
+func keepNone(int64, string) bool {
+	return false
+}
+
+// isOldEnough treats entities without creation time as old; it is not used for hubs
+func (this *Janitor) isOldEnough(createdAt time.Time, ok bool) bool {
+	return !ok || time.Since(createdAt) > this.maxAge
+}
+
+func (this *Janitor) cleanupDevices(token string, attribute string, keep keepFunc) {
+	for offset := int64(0); ; offset = offset + janitorPageSize {
+		this.metrics.DeviceRepoRequestCount.Inc()
+		start := time.Now()
+		devices, err, _ := this.devicerepo.ListDevices(token, model.DeviceListOptions{Limit: janitorPageSize, Offset: offset, AttributeKeys: []string{attribute}})
+		this.metrics.DeviceRepoRequestLatencyMs.Set(float64(time.Since(start).Milliseconds()))
+		if err != nil {
+			this.metrics.DeviceRepoRequestErr.Inc()
+			log.Println("ERROR: janitor cleanupDevices()", err)
+			return
+		}
+		for i, device := range devices {
+			if keep(offset+int64(i), device.Id) || !this.isOldEnough(CreatedAt(device.Attributes)) {
+				continue
+			}
+			this.deleteEntity(token, this.config.DeviceManagerUrl+"/devices/"+url.PathEscape(device.Id), this.metrics.JanitorDeletedDevices, "device", device.Id, device.Name)
+		}
+		if len(devices) < janitorPageSize {
+			return
+		}
+	}
+}
+
+func (this *Janitor) cleanupDeviceTypes(token string, attribute string, keep keepFunc) {
+	for offset := int64(0); ; offset = offset + janitorPageSize {
+		this.metrics.DeviceRepoRequestCount.Inc()
+		start := time.Now()
+		deviceTypes, _, err, _ := this.devicerepo.ListDeviceTypesV3(token, model.DeviceTypeListOptions{
+			Limit:         janitorPageSize,
+			Offset:        offset,
+			SortBy:        "name",
+			AttributeKeys: []string{attribute},
+		})
+		this.metrics.DeviceRepoRequestLatencyMs.Set(float64(time.Since(start).Milliseconds()))
+		if err != nil {
+			this.metrics.DeviceRepoRequestErr.Inc()
+			log.Println("ERROR: janitor cleanupDeviceTypes()", err)
+			return
+		}
+		for i, dt := range deviceTypes {
+			if keep(offset+int64(i), dt.Id) || !this.isOldEnough(CreatedAt(dt.Attributes)) {
+				continue
+			}
+			this.deleteEntity(token, this.config.DeviceManagerUrl+"/device-types/"+url.PathEscape(dt.Id), this.metrics.JanitorDeletedDeviceTypes, "device-type", dt.Id, dt.Name)
+		}
+		if len(deviceTypes) < janitorPageSize {
+			return
+		}
+	}
+}
+
+// cleanupDeviceGroups keeps the first group, which is reused by the canary group check
+func (this *Janitor) cleanupDeviceGroups(token string) {
+	for offset := int64(0); ; offset = offset + janitorPageSize {
+		this.metrics.DeviceRepoRequestCount.Inc()
+		start := time.Now()
+		groups, _, err, _ := this.devicerepo.ListDeviceGroups(token, model.DeviceGroupListOptions{
+			Limit:         janitorPageSize,
+			Offset:        offset,
+			AttributeKeys: []string{AttributeUsedForCanaryDeviceGroup},
+		})
+		this.metrics.DeviceRepoRequestLatencyMs.Set(float64(time.Since(start).Milliseconds()))
+		if err != nil {
+			this.metrics.DeviceRepoRequestErr.Inc()
+			log.Println("ERROR: janitor cleanupDeviceGroups()", err)
+			return
+		}
+		for i, group := range groups {
+			if keepFirst(offset+int64(i), group.Id) || !this.isOldEnough(CreatedAt(group.Attributes)) {
+				continue
+			}
+			this.deleteEntity(token, this.config.DeviceManagerUrl+"/device-groups/"+url.PathEscape(group.Id), this.metrics.JanitorDeletedDeviceGroups, "device-group", group.Id, group.Name)
+		}
+		if len(groups) < janitorPageSize {
+			return
+		}
+	}
+}
+
+// cleanupHubs deletes duplicates of the canary hub and old temporary hubs of other checks
+func (this *Janitor) cleanupHubs(token string, keepId string) {
+	for offset := int64(0); ; offset = offset + janitorPageSize {
+		this.metrics.DeviceRepoRequestCount.Inc()
+		start := time.Now()
+		hubs, err, _ := this.devicerepo.ListHubs(token, model.HubListOptions{Search: this.config.CanaryHubName, Limit: janitorPageSize, Offset: offset})
+		this.metrics.DeviceRepoRequestLatencyMs.Set(float64(time.Since(start).Milliseconds()))
+		if err != nil {
+			this.metrics.DeviceRepoRequestErr.Inc()
+			log.Println("ERROR: janitor cleanupHubs()", err)
+			return
+		}
+		for _, hub := range hubs {
+			if hub.Id == keepId || !this.isDeletableHub(hub) {
+				continue
+			}
+			this.deleteEntity(token, this.config.DeviceManagerUrl+"/hubs/"+url.PathEscape(hub.Id), this.metrics.JanitorDeletedHubs, "hub", hub.Id, hub.Name)
+		}
+		if len(hubs) < janitorPageSize {
+			return
+		}
+	}
+}
+
+// isDeletableHub returns true for other hubs with the exact canary hub name and for old temporary hubs.
+// hubs without creation time are kept.
+func (this *Janitor) isDeletableHub(hub models.Hub) bool {
+	if hub.Name == this.config.CanaryHubName {
+		return true
+	}
+	createdAt, ok := hubCreatedAt(this.config.CanaryHubName, hub.Name)
+	return ok && time.Since(createdAt) > this.maxAge
+}
+
+// cleanupProcessDeployments keeps the newest deployment per canary process name.
+// deployments are listed and deleted with the same process platform client as in the process checks.
+func (this *Janitor) cleanupProcessDeployments(token string) {
+	deployments, err := this.platform.ListDeployments(token)
+	if err != nil {
+		this.metrics.JanitorErr.Inc()
+		log.Println("ERROR: janitor cleanupProcessDeployments()", err)
+		return
+	}
+	for _, name := range CanaryProcessDeploymentNames {
+		newest := processplatform.Wrapper{}
+		newestTime := time.Time{}
+		for _, depl := range deployments {
+			if depl.Name != name {
+				continue
+			}
+			if t, _ := depl.DeployedAt(); newest.Id == "" || t.After(newestTime) {
+				newest = depl
+				newestTime = t
+			}
+		}
+		for _, depl := range deployments {
+			if depl.Name != name || depl.Id == newest.Id {
+				continue
+			}
+			deployedAt, err := depl.DeployedAt()
+			if !this.isOldEnough(deployedAt, err == nil) {
+				continue
+			}
+			err = this.platform.DeleteDeployment(token, depl.Id)
+			if err != nil {
+				this.metrics.JanitorErr.Inc()
+				log.Println("ERROR: janitor cleanupProcessDeployments()", depl.Id, err)
+				continue
+			}
+			this.metrics.JanitorDeletedProcessDeployments.Inc()
+			log.Println("janitor: deleted leaked canary process deployment", depl.Id, depl.Name, depl.DeploymentTime)
+		}
+	}
+}
+
+func (this *Janitor) deleteEntity(token string, endpoint string, deleted prometheus.Counter, kind string, id string, name string) {
+	if this.delete(token, endpoint) == nil {
+		deleted.Inc()
+		log.Println("janitor: deleted leaked canary", kind, id, name)
+	}
+}
+
+func (this *Janitor) delete(token string, endpoint string) (err error) {
+	this.metrics.DeviceMetaUpdateCount.Inc()
+	req, err := http.NewRequest(http.MethodDelete, endpoint, nil)
+	if err != nil {
+		this.metrics.JanitorErr.Inc()
+		log.Println("ERROR: janitor delete()", err)
+		return err
+	}
+	req.Header.Set("Authorization", token)
+	start := time.Now()
+	_, err = DoWithoutResult(req)
+	this.metrics.DeviceMetaUpdateLatencyMs.Set(float64(time.Since(start).Milliseconds()))
+	if err != nil {
+		this.metrics.JanitorErr.Inc()
+		log.Println("ERROR: janitor delete()", endpoint, err)
+	}
+	return err
+}
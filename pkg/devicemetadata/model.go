@@ -31,6 +31,20 @@ type PermDevice struct {
 }
 
 const AttributeUsedForCanaryDevice = "senergy/snowflake-canary-device"
+const AttributeUsedForTemporaryCanaryDevice = "senergy/snowflake-canary-temporary-device"
 const AttributeUsedForCanaryDeviceType = "senergy/snowflake-canary-device-type"
+const AttributeUsedForSecondaryCanaryDevice = "senergy/snowflake-canary-secondary-device"
+const AttributeUsedForSecondaryCanaryDeviceType = "senergy/snowflake-canary-secondary-device-type"
+const AttributeUsedForCanaryDeviceGroup = "senergy/snowflake-canary-device-group"
 const SensorServiceLocalId = "sensor"
+
+// TimePathAttributeKey is the service attribute, which points to the value time of device messages
+const TimePathAttributeKey = "senergy/time_path"
+
+// SensorTimePath points to the RFC 3339 time attribute of the canary sensor measurement
+const SensorTimePath = "measurements.measurement.time"
 const CmdServiceLocalId = "cmd"
+
+// AttributeUsedForMetadataCheck is a non-canary attribute, changed by TestMetadata like a user would change a display name
+const AttributeUsedForMetadataCheck = "senergy/snowflake-canary-metadata-check"
+const AttributeOriginForMetadataCheck = "web-ui"
@@ -0,0 +1,166 @@
+/*
+ * Copyright (c) 2023 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package devicemetadata
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/SENERGY-Platform/device-repository/lib/model"
+	"github.com/SENERGY-Platform/models/go/models"
+	"github.com/google/uuid"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"time"
+)
+
+// SecondaryAreaStructValueName is the name of the sub content variable used by the structured area shape
+const SecondaryAreaStructValueName = "value"
+
+// GetSecondaryCanaryDeviceTypeSpec returns the canary device type spec with a different name and marker attribute.
+// the area output is either a plain value ("area") or a structure with a single value ("area.value").
+// the secondary device type is changed by the canary, so it must not be used by other checks.
+func (this *DeviceMetaData) GetSecondaryCanaryDeviceTypeSpec(structuredArea bool) models.DeviceType {
+	dt := this.GetCanaryDeviceTypeSpec()
+	dt.Name = "snowflake-canary-secondary-device-type"
+	dt.Attributes = []models.Attribute{{
+		Key:    AttributeUsedForSecondaryCanaryDeviceType,
+		Value:  "true",
+		Origin: "canary",
+	}}
+	for i, service := range dt.Services {
+		if service.LocalId != SensorServiceLocalId {
+			continue
+		}
+		//messages of the secondary device only carry the area segment and use the receive time
+		dt.Services[i].Attributes = nil
+		for j, content := range service.Outputs {
+			if content.ProtocolSegmentId != this.config.CanaryProtocolSegmentId2 || !structuredArea {
+				continue
+			}
+			value := content.ContentVariable
+			value.Name = SecondaryAreaStructValueName
+			dt.Services[i].Outputs[j].ContentVariable = models.ContentVariable{
+				Name:                content.ContentVariable.Name,
+				Type:                models.Structure,
+				SubContentVariables: []models.ContentVariable{value},
+			}
+		}
+	}
+	return dt
+}
+
+// HasStructuredArea returns true if the area output of the device type uses the structured shape
+func (this *DeviceMetaData) HasStructuredArea(dt models.DeviceType) bool {
+	for _, service := range dt.Services {
+		if service.LocalId != SensorServiceLocalId {
+			continue
+		}
+		for _, content := range service.Outputs {
+			if content.ProtocolSegmentId == this.config.CanaryProtocolSegmentId2 {
+				return content.ContentVariable.Type == models.Structure
+			}
+		}
+	}
+	return false
+}
+
+// EnsureSecondaryDeviceType returns the secondary canary device type; it is created with the plain area shape if missing
+func (this *DeviceMetaData) EnsureSecondaryDeviceType(token string) (dt models.DeviceType, err error) {
+	this.metrics.DeviceRepoRequestCount.Inc()
+	start := time.Now()
+	deviceTypes, _, err, _ := this.devicerepo.ListDeviceTypesV3(token, model.DeviceTypeListOptions{
+		Limit:         1,
+		Offset:        0,
+		SortBy:        "name",
+		AttributeKeys: []string{AttributeUsedForSecondaryCanaryDeviceType},
+	})
+	this.metrics.DeviceRepoRequestLatencyMs.Set(float64(time.Since(start).Milliseconds()))
+	if err != nil {
+		this.metrics.DeviceRepoRequestErr.Inc()
+		log.Println("ERROR:", err)
+		debug.PrintStack()
+		return dt, err
+	}
+	if len(deviceTypes) > 0 {
+		return this.readDeviceType(token, deviceTypes[0].Id)
+	}
+	dt, err = this.CreateDeviceType(token, this.GetSecondaryCanaryDeviceTypeSpec(false))
+	time.Sleep(this.getChangeGuaranteeDuration())
+	return dt, err
+}
+
+// EnsureSecondaryDevice returns the device of the secondary canary device type; it is created if missing
+func (this *DeviceMetaData) EnsureSecondaryDevice(token string, deviceTypeId string) (device DeviceInfo, err error) {
+	this.metrics.DeviceRepoRequestCount.Inc()
+	start := time.Now()
+	devices, err, _ := this.devicerepo.ListDevices(token, model.DeviceListOptions{Limit: 1, AttributeKeys: []string{AttributeUsedForSecondaryCanaryDevice}})
+	this.metrics.DeviceRepoRequestLatencyMs.Set(float64(time.Since(start).Milliseconds()))
+	if err != nil {
+		this.metrics.DeviceRepoRequestErr.Inc()
+		log.Println("ERROR:", err)
+		debug.PrintStack()
+		return device, err
+	}
+	if len(devices) > 0 {
+		return devices[0], nil
+	}
+	device, err = this.CreateDevice(token, DeviceInfo{
+		LocalId: "snowflake-canary-secondary_" + uuid.NewString(),
+		Name:    "snowflake-canary-secondary-" + time.Now().String(),
+		Attributes: []models.Attribute{{
+			Key:    AttributeUsedForSecondaryCanaryDevice,
+			Value:  "true",
+			Origin: "canary",
+		}},
+		DeviceTypeId: deviceTypeId,
+	})
+	time.Sleep(this.getChangeGuaranteeDuration())
+	return device, err
+}
+
+func (this *DeviceMetaData) CreateDeviceType(token string, dt models.DeviceType) (result models.DeviceType, err error) {
+	dt.Attributes = WithCreatedAt(dt.Attributes, nil)
+	buf := &bytes.Buffer{}
+	err = json.NewEncoder(buf).Encode(dt)
+	if err != nil {
+		return result, err
+	}
+	this.metrics.DeviceMetaUpdateCount.Inc()
+	req, err := http.NewRequest(http.MethodPost, this.config.DeviceManagerUrl+"/device-types?wait=true", buf)
+	if err != nil {
+		this.metrics.UncategorizedErr.Inc()
+		log.Println("ERROR:", err)
+		debug.PrintStack()
+		return result, err
+	}
+	req.Header.Set("Authorization", token)
+	start := time.Now()
+	result, _, err = Do[models.DeviceType](req)
+	this.metrics.DeviceMetaUpdateLatencyMs.Set(float64(time.Since(start).Milliseconds()))
+	if err != nil {
+		this.metrics.DeviceMetaUpdateErr.Inc()
+		log.Println("ERROR:", err)
+		debug.PrintStack()
+	}
+	return result, err
+}
+
+// SwitchSecondaryDeviceType updates the secondary device type to the requested area shape, keeping the existing ids
+func (this *DeviceMetaData) SwitchSecondaryDeviceType(token string, current models.DeviceType, structuredArea bool) error {
+	return this.UpdateDeviceType(token, withIdsOf(this.GetSecondaryCanaryDeviceTypeSpec(structuredArea), current))
+}
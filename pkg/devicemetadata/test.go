@@ -19,38 +19,112 @@ package devicemetadata
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	devicemodel "github.com/SENERGY-Platform/device-repository/lib/model"
+	"github.com/SENERGY-Platform/models/go/models"
 	"log"
 	"net/http"
 	"net/url"
 	"runtime/debug"
+	"slices"
+	"strings"
 	"time"
 )
 
 func (this *DeviceMetaData) TestMetadata(token string, info DeviceInfo) {
 	//read current device
+	d, err := this.readDevice(token, info.Id)
+	if err != nil {
+		return
+	}
+
+	//remove leftovers of interrupted runs
+	d.Attributes = slices.DeleteFunc(d.Attributes, func(attr models.Attribute) bool {
+		return attr.Key == AttributeUsedForMetadataCheck
+	})
+
+	//set name and add attribute
+	d.Name = "snowflake-canary-" + time.Now().String()
+	d.Attributes = append(d.Attributes, models.Attribute{
+		Key:    AttributeUsedForMetadataCheck,
+		Value:  time.Now().Format(time.RFC3339Nano),
+		Origin: AttributeOriginForMetadataCheck,
+	})
+	if !this.updateAndCheckDevice(token, d) {
+		return
+	}
+
+	//modify attribute
+	for i, attr := range d.Attributes {
+		if attr.Key == AttributeUsedForMetadataCheck {
+			d.Attributes[i].Value = time.Now().Format(time.RFC3339Nano)
+		}
+	}
+	if !this.updateAndCheckDevice(token, d) {
+		return
+	}
+
+	//remove attribute
+	d.Attributes = slices.DeleteFunc(d.Attributes, func(attr models.Attribute) bool {
+		return attr.Key == AttributeUsedForMetadataCheck
+	})
+	this.updateAndCheckDevice(token, d)
+}
+
+// updateAndCheckDevice saves the device and compares name and attributes in the device-repository with the saved device.
+// returns false if the device could not be saved or read.
+func (this *DeviceMetaData) updateAndCheckDevice(token string, d models.Device) bool {
+	err := this.updateDevice(token, d)
+	if err != nil {
+		return false
+	}
+
+	time.Sleep(this.getChangeGuaranteeDuration()) //wait for cqrs
+
+	//check device-repo for changes
+	repoDevice, err := this.readDevice(token, d.Id)
+	if err != nil {
+		return false
+	}
+
+	if repoDevice.Name != d.Name {
+		this.metrics.UnexpectedDeviceRepoMetadataErr.Inc()
+		log.Printf("UnexpectedDeviceRepoMetadataErr: %#v != %#v\n", repoDevice.Name, d.Name)
+	}
+	if diff := AttributeDiff(d.Attributes, repoDevice.Attributes); len(diff) > 0 {
+		this.metrics.UnexpectedDeviceRepoAttributeErr.Inc()
+		log.Printf("UnexpectedDeviceRepoAttributeErr:\n%v\n", strings.Join(diff, "\n"))
+	}
+	if !slices.ContainsFunc(repoDevice.Attributes, func(attr models.Attribute) bool {
+		return attr.Key == AttributeUsedForCanaryDevice
+	}) {
+		this.metrics.UnexpectedDeviceRepoAttributeErr.Inc()
+		log.Printf("UnexpectedDeviceRepoAttributeErr: missing %v attribute\n", AttributeUsedForCanaryDevice)
+	}
+	return true
+}
+
+func (this *DeviceMetaData) readDevice(token string, id string) (d models.Device, err error) {
 	this.metrics.DeviceRepoRequestCount.Inc()
 	start := time.Now()
-	d, err, _ := this.devicerepo.ReadDevice(info.Id, token, devicemodel.READ)
+	d, err, _ = this.devicerepo.ReadDevice(id, token, devicemodel.READ)
 	this.metrics.DeviceRepoRequestLatencyMs.Set(float64(time.Since(start).Milliseconds()))
 	if err != nil {
 		this.metrics.DeviceRepoRequestErr.Inc()
 		log.Println("ERROR:", err)
 		debug.PrintStack()
-		return
 	}
+	return d, err
+}
 
-	//set name
-	d.Name = "snowflake-canary-" + time.Now().String()
-
-	//save device with changed name
+func (this *DeviceMetaData) updateDevice(token string, d models.Device) (err error) {
 	buf := &bytes.Buffer{}
 	err = json.NewEncoder(buf).Encode(d)
 	if err != nil {
 		this.metrics.UncategorizedErr.Inc()
 		log.Println("ERROR:", err)
 		debug.PrintStack()
-		return
+		return err
 	}
 	this.metrics.DeviceMetaUpdateCount.Inc()
 	req, err := http.NewRequest(http.MethodPut, this.config.DeviceManagerUrl+"/devices/"+url.PathEscape(d.Id), buf)
@@ -58,10 +132,10 @@ func (this *DeviceMetaData) TestMetadata(token string, info DeviceInfo) {
 		this.metrics.UncategorizedErr.Inc()
 		log.Println("ERROR:", err)
 		debug.PrintStack()
-		return
+		return err
 	}
 	req.Header.Set("Authorization", token)
-	start = time.Now()
+	start := time.Now()
 	_, _, err = Do[DeviceInfo](req)
 	this.metrics.DeviceMetaUpdateLatencyMs.Set(float64(time.Since(start).Milliseconds()))
 	if err != nil {
@@ -69,23 +143,35 @@ func (this *DeviceMetaData) TestMetadata(token string, info DeviceInfo) {
 		log.Println("ERROR:", err)
 		debug.PrintStack()
 	}
+	return err
+}
 
-	time.Sleep(this.getChangeGuaranteeDuration()) //wait for cqrs
-
-	//check device-repo for name change
-	this.metrics.DeviceRepoRequestCount.Inc()
-	start = time.Now()
-	repoDevice, err, _ := this.devicerepo.ReadDevice(info.Id, token, devicemodel.READ)
-	this.metrics.DeviceRepoRequestLatencyMs.Set(float64(time.Since(start).Milliseconds()))
-	if err != nil {
-		this.metrics.DeviceRepoRequestErr.Inc()
-		log.Println("ERROR:", err)
-		debug.PrintStack()
-		return
+// AttributeDiff lists the differences between the expected and the actual attribute set; attributes are matched by key
+func AttributeDiff(expected []models.Attribute, actual []models.Attribute) (diff []string) {
+	if len(expected) != len(actual) {
+		diff = append(diff, fmt.Sprintf("attribute count: %v != %v", len(actual), len(expected)))
 	}
-
-	if repoDevice.Name != d.Name {
-		this.metrics.UnexpectedDeviceRepoMetadataErr.Inc()
-		log.Printf("UnexpectedDeviceRepoMetadataErr: %#v != %#v\n", repoDevice.Name, d.Name)
+	for _, e := range expected {
+		i := slices.IndexFunc(actual, func(a models.Attribute) bool {
+			return a.Key == e.Key
+		})
+		if i < 0 {
+			diff = append(diff, fmt.Sprintf("missing attribute %v", e.Key))
+			continue
+		}
+		if actual[i].Value != e.Value {
+			diff = append(diff, fmt.Sprintf("%v.value: %#v != %#v", e.Key, actual[i].Value, e.Value))
+		}
+		if actual[i].Origin != e.Origin {
+			diff = append(diff, fmt.Sprintf("%v.origin: %#v != %#v", e.Key, actual[i].Origin, e.Origin))
+		}
+	}
+	for _, a := range actual {
+		if !slices.ContainsFunc(expected, func(e models.Attribute) bool {
+			return a.Key == e.Key
+		}) {
+			diff = append(diff, fmt.Sprintf("unexpected attribute %v", a.Key))
+		}
 	}
+	return diff
 }
@@ -0,0 +1,67 @@
+/*
+ * Copyright (c) 2023 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package devicemetadata
+
+import (
+	"github.com/SENERGY-Platform/models/go/models"
+	"log"
+	"slices"
+)
+
+// HasSensorTimePath returns true if the service points to the time of the canary sensor measurement
+func HasSensorTimePath(service models.Service) bool {
+	return slices.ContainsFunc(service.Attributes, func(attr models.Attribute) bool {
+		return attr.Key == TimePathAttributeKey && attr.Value == SensorTimePath
+	})
+}
+
+// migrateSensorTimePath adds the time path and the time content variable to canary device types, which have been created before both existed.
+// the migration runs independent of update_drifted_device_type, because the value time checks depend on it;
+// other differences to the spec are left to CheckDeviceTypeDrift.
+func (this *DeviceMetaData) migrateSensorTimePath(token string, dt models.DeviceType) (models.DeviceType, error) {
+	spec := this.GetCanaryDeviceTypeSpec()
+	specServiceIndex := slices.IndexFunc(spec.Services, isSensorService)
+	serviceIndex := slices.IndexFunc(dt.Services, isSensorService)
+	if specServiceIndex < 0 || serviceIndex < 0 || HasSensorTimePath(dt.Services[serviceIndex]) {
+		return dt, nil
+	}
+	specService := spec.Services[specServiceIndex]
+	service := dt.Services[serviceIndex]
+	service.Attributes = append(slices.DeleteFunc(slices.Clone(service.Attributes), func(attr models.Attribute) bool {
+		return attr.Key == TimePathAttributeKey
+	}), models.Attribute{Key: TimePathAttributeKey, Value: SensorTimePath, Origin: "canary"})
+	service.Outputs = slices.Clone(service.Outputs)
+	for i, content := range service.Outputs {
+		for _, specContent := range specService.Outputs {
+			if specContent.ProtocolSegmentId == content.ProtocolSegmentId && specContent.ProtocolSegmentId == this.config.CanaryProtocolSegmentId {
+				service.Outputs[i].ContentVariable = contentVariableWithIdsOf(specContent.ContentVariable, content.ContentVariable)
+			}
+		}
+	}
+	dt.Services = slices.Clone(dt.Services)
+	dt.Services[serviceIndex] = service
+
+	log.Printf("WARNING: migrate canary device type %v to the sensor time path %v\n", dt.Id, SensorTimePath)
+	err := this.UpdateDeviceType(token, dt)
+	if err != nil {
+		return dt, err
+	}
+	return this.readDeviceType(token, dt.Id)
+}
+
+func isSensorService(service models.Service) bool {
+	return service.LocalId == SensorServiceLocalId
+}
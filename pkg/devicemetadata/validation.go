@@ -0,0 +1,82 @@
+/*
+ * Copyright (c) 2023 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package devicemetadata
+
+import (
+	"github.com/SENERGY-Platform/device-repository/lib/model"
+	"github.com/SENERGY-Platform/models/go/models"
+	"log"
+	"net/http"
+	"time"
+)
+
+type InvalidDeviceTypeCase struct {
+	Name       string
+	DeviceType models.DeviceType
+}
+
+// GetInvalidDeviceTypeCases returns variants of the canary device type spec, which must be rejected by the validation
+func (this *DeviceMetaData) GetInvalidDeviceTypeCases() []InvalidDeviceTypeCase {
+	unknownSegment := this.GetCanaryDeviceTypeSpec()
+	unknownSegment.Services[1].Outputs[0].ProtocolSegmentId = "urn:infai:ses:protocol-segment:snowflake-canary-unknown"
+
+	duplicateLocalId := this.GetCanaryDeviceTypeSpec()
+	duplicateLocalId.Services[0].LocalId = duplicateLocalId.Services[1].LocalId
+
+	functionWithoutCharacteristic := this.GetCanaryDeviceTypeSpec()
+	functionWithoutCharacteristic.Services[1].Outputs[1].ContentVariable.CharacteristicId = ""
+
+	return []InvalidDeviceTypeCase{
+		{Name: "unknown_protocol_segment", DeviceType: unknownSegment},
+		{Name: "duplicate_service_local_id", DeviceType: duplicateLocalId},
+		{Name: "function_without_characteristic", DeviceType: functionWithoutCharacteristic},
+	}
+}
+
+// TestValidation submits invalid metadata to the validation endpoints and expects a 4xx response for every case
+func (this *DeviceMetaData) TestValidation(token string, info DeviceInfo) {
+	for _, c := range this.GetInvalidDeviceTypeCases() {
+		this.metrics.DeviceRepoRequestCount.Inc()
+		start := time.Now()
+		err, code := this.devicerepo.ValidateDeviceType(c.DeviceType, model.ValidationOptions{})
+		this.metrics.DeviceRepoRequestLatencyMs.Set(float64(time.Since(start).Milliseconds()))
+		this.checkValidationRejected(c.Name, err, code)
+	}
+
+	this.metrics.DeviceRepoRequestCount.Inc()
+	start := time.Now()
+	err, code := this.devicerepo.ValidateDevice(token, models.Device{
+		LocalId:      info.LocalId,
+		Name:         "snowflake-canary-duplicate-local-id",
+		DeviceTypeId: info.DeviceTypeId,
+	})
+	this.metrics.DeviceRepoRequestLatencyMs.Set(float64(time.Since(start).Milliseconds()))
+	this.checkValidationRejected("duplicate_device_local_id", err, code)
+}
+
+func (this *DeviceMetaData) checkValidationRejected(name string, err error, code int) {
+	this.metrics.ValidationCheckCount.WithLabelValues(name).Inc()
+	switch {
+	case (err != nil && code == 0) || code >= http.StatusInternalServerError:
+		//transport or server errors say nothing about the validation
+		this.metrics.DeviceRepoRequestErr.Inc()
+		log.Printf("ERROR: validation %v: %v %v\n", name, code, err)
+	case err == nil && code < 400:
+		this.metrics.UnexpectedValidationResultErr.WithLabelValues(name).Inc()
+		log.Printf("UnexpectedValidationResultErr: %v: invalid metadata was accepted (%v)\n", name, code)
+	}
+}
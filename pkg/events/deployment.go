@@ -0,0 +1,84 @@
+/*
+ * Copyright (c) 2023 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package events
+
+import (
+	"errors"
+	"github.com/SENERGY-Platform/snowflake-canary/pkg/bpmn"
+	"github.com/SENERGY-Platform/snowflake-canary/pkg/devicemetadata"
+	"strconv"
+)
+
+const CanaryEventProcessId = "snowflake_canary_event_process"
+const EventStartBpmnId = "StartEvent_1"
+const EventValueVariable = "value"
+
+// EventThreshold separates matching from non-matching sensor values; values <= EventThreshold must not start the event process
+const EventThreshold = 1000
+
+// getProcessConversion returns the conversion of sensor values to canary_process_characteristic_id, in which the event script compares values
+func (this *Events) getProcessConversion() (devicemetadata.CharacteristicConversion, error) {
+	return devicemetadata.GetSensorConversion(this.config, this.config.CanaryProcessCharacteristicId)
+}
+
+// getEventScript starts the event process for values above EventThreshold, converted to canary_process_characteristic_id
+func (this *Events) getEventScript() (string, error) {
+	conversion, err := this.getProcessConversion()
+	if err != nil {
+		return "", err
+	}
+	return "value > " + strconv.FormatFloat(conversion.Convert(EventThreshold), 'f', -1, 64), nil
+}
+
+// getProcessBuilder creates the event process: conditional start on canary sensor values -> end
+func (this *Events) getProcessBuilder() (*bpmn.Builder, error) {
+	script, err := this.getEventScript()
+	if err != nil {
+		return nil, err
+	}
+	builder := bpmn.NewBuilder(CanaryEventProcessId)
+	builder.Chain(
+		builder.ConditionalStartEvent(EventStartBpmnId, "Get Temperature\n"+script, bpmn.ConditionalEventInfo{
+			FunctionId:        this.config.CanarySensorFunctionId,
+			AspectId:          this.config.CanarySensorAspectId,
+			CharacteristicId:  this.config.CanaryProcessCharacteristicId,
+			Script:            script,
+			ValueVariableName: EventValueVariable,
+			Qos:               1,
+		}),
+		builder.EndEvent("EndEvent_1"),
+	)
+	return builder, nil
+}
+
+func (this *Events) getDeploymentMessage(deviceId string, serviceId string) (deployment bpmn.Deployment, err error) {
+	builder, err := this.getProcessBuilder()
+	if err != nil {
+		return deployment, err
+	}
+	deployment, err = builder.Deployment(ExpectedCanaryDeploymentName)
+	if err != nil {
+		return deployment, err
+	}
+	deployment.Description = "no description"
+	start := deployment.Element(EventStartBpmnId)
+	if start == nil || start.ConditionalEvent == nil {
+		return deployment, errors.New("unexpected deployment: missing conditional start event")
+	}
+	start.ConditionalEvent.Selection.SelectDevice(deviceId, serviceId)
+	return deployment, nil
+}
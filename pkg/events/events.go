@@ -23,7 +23,9 @@ import (
 	"github.com/SENERGY-Platform/snowflake-canary/pkg/configuration"
 	"github.com/SENERGY-Platform/snowflake-canary/pkg/devicemetadata"
 	"github.com/SENERGY-Platform/snowflake-canary/pkg/metrics"
+	"github.com/SENERGY-Platform/snowflake-canary/pkg/processplatform"
 	"log"
+	"math"
 	"time"
 )
 
@@ -31,16 +33,21 @@ type Events struct {
 	config               configuration.Config
 	devicerepo           devicerepo.Interface
 	guaranteeChangeAfter time.Duration
+	platform             *processplatform.Client
+	negativeWindow       time.Duration
+	deploymentId         string
 	metrics              *metrics.Metrics
 }
 
 type DeviceInfo = devicemetadata.DeviceInfo
 
-func New(config configuration.Config, devicerepo devicerepo.Interface, metrics *metrics.Metrics, guaranteeChangeAfter time.Duration) *Events {
+func New(config configuration.Config, devicerepo devicerepo.Interface, metrics *metrics.Metrics, platform *processplatform.Client, guaranteeChangeAfter time.Duration, negativeWindow time.Duration) *Events {
 	return &Events{
 		config:               config,
 		devicerepo:           devicerepo,
 		guaranteeChangeAfter: guaranteeChangeAfter,
+		platform:             platform,
+		negativeWindow:       negativeWindow,
 		metrics:              metrics,
 	}
 }
@@ -50,20 +57,16 @@ func (this *Events) getChangeGuaranteeDuration() time.Duration {
 }
 
 func (this *Events) ProcessStartup(token string, info DeviceInfo) error {
-	ids, err := this.ListCanaryProcessDeployments(token)
+	this.deploymentId = ""
+	_, err := this.getProcessConversion()
 	if err != nil {
 		this.metrics.UncategorizedErr.Inc()
-		log.Println("ERROR: unexpected event process deployment list count")
+		log.Println("ERROR: unable to check event process values", err)
 		return err
 	}
-	//cleanup
-	for _, id := range ids {
-		err = this.DeleteProcess(token, id)
-		if err != nil {
-			this.metrics.UncategorizedErr.Inc()
-			log.Println("ERROR: DeleteProcess()", err)
-			return err
-		}
+	err = this.platform.DeleteDeploymentsByName(token, ExpectedCanaryDeploymentName)
+	if err != nil {
+		return err
 	}
 
 	dt, err, _ := this.devicerepo.ReadDeviceType(info.DeviceTypeId, token)
@@ -92,7 +95,7 @@ func (this *Events) ProcessStartup(token string, info DeviceInfo) error {
 		foundService := false
 		foundDevice := false
 		for _, e := range preparedDepl.Elements {
-			if e.BpmnId == "StartEvent_1" && e.ConditionalEvent != nil {
+			if e.BpmnId == EventStartBpmnId && e.ConditionalEvent != nil {
 				for _, o := range e.ConditionalEvent.Selection.SelectionOptions {
 					if o.Device != nil && o.Device.Id == info.Id {
 						foundDevice = true
@@ -116,19 +119,21 @@ func (this *Events) ProcessStartup(token string, info DeviceInfo) error {
 		}
 	}
 
-	_, err = this.DeployProcess(token, info.Id, serviceId)
+	deplId, err := this.DeployProcess(token, info.Id, serviceId)
 	if err != nil {
 		this.metrics.EventProcessDeploymentErr.Inc()
 		log.Println("ERROR: EventProcessDeploymentErr", err)
 		return err
 	}
+	this.deploymentId = deplId
 
 	time.Sleep(this.getChangeGuaranteeDuration())
 
 	return nil
 }
 
-func (this *Events) ProcessTeardown(token string) error {
+// ProcessTeardown expects exactly one completed instance, started by publishedValue at publishedAt
+func (this *Events) ProcessTeardown(token string, publishedValue int, publishedAt time.Time) error {
 	ids, err := this.ListCanaryProcessDeployments(token)
 	if err != nil {
 		this.metrics.UncategorizedErr.Inc()
@@ -139,40 +144,67 @@ func (this *Events) ProcessTeardown(token string) error {
 		log.Println("ERROR: unexpected process deployment list count")
 	}
 
-	unfilteredInstances, err := this.GetProcessInstances(token)
-	instances := []ProcessInstance{}
-	for _, e := range unfilteredInstances {
-		if e.ProcessDefinitionName == ExpectedCanaryDeploymentName {
-			instances = append(instances, e)
+	if this.deploymentId != "" {
+		instances, err := this.platform.WaitForFinishedInstances(token, this.deploymentId, 1)
+		switch {
+		case errors.Is(err, processplatform.ErrInstanceTimeout):
+			this.metrics.EventProcessInstanceTimeoutErr.Inc()
+			log.Printf("ERROR: EventProcessInstanceTimeoutErr %#v \n", instances)
+		case err != nil:
+			this.metrics.UncategorizedErr.Inc()
+			log.Println("ERROR: unable to get event process instances", err)
+		case len(instances) != 1:
+			this.metrics.UnexpectedEventProcessInstanceCountErr.Inc()
+			log.Printf("ERROR: UnexpectedEventProcessInstanceCountErr instance-count=%v deployment-count=%v\n", len(instances), len(ids))
+		case instances[0].State != "COMPLETED":
+			this.metrics.UnexpectedEventProcessInstanceStateErr.Inc()
+			log.Printf("ERROR: UnexpectedProcessInstanceStateErr %#v \n", instances)
+		default:
+			this.metrics.EventProcessInstanceDurationMs.Set(float64(instances[0].DurationInMillis))
+			this.checkInstance(token, instances[0], publishedValue, publishedAt)
 		}
+		this.platform.DeleteFinishedInstanceHistory(token, instances)
 	}
 
+	return this.platform.DeleteDeploymentsByName(token, ExpectedCanaryDeploymentName)
+}
+
+// checkInstance exports the latency between publish and instance start and compares the value variable with the published value,
+// converted from canary_sensor_characteristic_id to canary_process_characteristic_id.
+// publishedAt is taken before the publish, so the latency includes the mqtt transfer.
+func (this *Events) checkInstance(token string, instance ProcessInstance, publishedValue int, publishedAt time.Time) {
+	startedAt, err := instance.StartedAt()
 	if err != nil {
 		this.metrics.UncategorizedErr.Inc()
-		log.Println("ERROR: unexpected event process list count", err)
+		log.Println("ERROR: unable to parse event process instance start time", instance.StartTime, err)
 	} else {
-		if len(instances) != 1 {
-			this.metrics.UncategorizedErr.Inc()
-			log.Printf("ERROR: unexpected event process instance list count instance-count=%v deployment-count=%v unfiltered-instance-count=%v\n", len(instances), len(ids), len(unfilteredInstances))
-		} else {
-			if instances[0].State != "COMPLETED" {
-				this.metrics.UnexpectedEventProcessInstanceStateErr.Inc()
-				log.Printf("ERROR: UnexpectedProcessInstanceStateErr %#v \n", instances)
-			} else {
-				this.metrics.EventProcessInstanceDurationMs.Set(float64(instances[0].DurationInMillis))
-			}
-		}
+		this.metrics.EventProcessStartLatencyMs.Set(float64(startedAt.Sub(publishedAt).Milliseconds()))
 	}
 
-	//cleanup
-	for _, id := range ids {
-		err = this.DeleteProcess(token, id)
-		if err != nil {
-			this.metrics.UncategorizedErr.Inc()
-			log.Println("ERROR: DeleteProcess()", err)
-			return err
+	variables, err := this.platform.GetProcessInstanceHistoryVariables(token, instance.Id)
+	if err != nil {
+		this.metrics.UncategorizedErr.Inc()
+		log.Println("ERROR: GetProcessInstanceHistoryVariables()", err)
+		return
+	}
+	conversion, err := this.getProcessConversion()
+	if err != nil {
+		this.metrics.UncategorizedErr.Inc()
+		log.Println("ERROR:", err)
+		return
+	}
+	expected := conversion.Convert(float64(publishedValue))
+	for _, variable := range variables {
+		if variable.Name != EventValueVariable {
+			continue
+		}
+		value, err := variable.Float()
+		if err != nil || math.Abs(value-expected) > math.Abs(expected)*conversion.Tolerance {
+			this.metrics.UnexpectedEventProcessVariableErr.Inc()
+			log.Printf("ERROR: UnexpectedEventProcessVariableErr %v = %#v, expected %v (%v); %v\n", EventValueVariable, variable.Value, expected, conversion.Name, err)
 		}
+		return
 	}
-
-	return nil
+	this.metrics.UnexpectedEventProcessVariableErr.Inc()
+	log.Println("ERROR: UnexpectedEventProcessVariableErr missing variable", EventValueVariable)
 }
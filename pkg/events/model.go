@@ -16,55 +16,12 @@
 
 package events
 
-type ProcessInstance struct {
-	Id                    string `json:"id"`
-	ProcessDefinitionName string `json:"processDefinitionName"`
-	StartTime             string `json:"startTime"`
-	EndTime               string `json:"endTime"`
-	DurationInMillis      int    `json:"durationInMillis"`
-	State                 string `json:"state"`
-}
+import "github.com/SENERGY-Platform/snowflake-canary/pkg/processplatform"
 
-type PreparedDeployment struct {
-	Id       string    `json:"id"`
-	Name     string    `json:"name"`
-	Elements []Element `json:"elements"`
-}
+type ProcessInstance = processplatform.ProcessInstance
 
-type Element struct {
-	BpmnId           string            `json:"bpmn_id"`
-	ConditionalEvent *ConditionalEvent `json:"conditional_event"`
-}
+type ProcessDefinition = processplatform.ProcessDefinition
 
-type ConditionalEvent struct {
-	Script        string            `json:"script"`
-	ValueVariable string            `json:"value_variable"`
-	Variables     map[string]string `json:"variables"`
-	Qos           int               `json:"qos"`
-	EventId       string            `json:"event_id"`
-	Selection     Selection         `json:"selection"`
-}
+type PreparedDeployment = processplatform.PreparedDeployment
 
-type Selection struct {
-	SelectionOptions []SelectionOption `json:"selection_options"`
-}
-
-type SelectionOption struct {
-	Device   *Device   `json:"device"`
-	Services []Service `json:"services"`
-}
-
-type Device struct {
-	Id   string `json:"id"`
-	Name string `json:"name"`
-}
-
-type DeviceGroup struct {
-	Id   string `json:"id"`
-	Name string `json:"name"`
-}
-
-type Service struct {
-	Id   string `json:"id"`
-	Name string `json:"name"`
-}
+type HistoricVariable = processplatform.HistoricVariable
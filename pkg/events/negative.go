@@ -0,0 +1,59 @@
+/*
+ * Copyright (c) 2023 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package events
+
+import (
+	"log"
+	"math/rand"
+	"time"
+)
+
+// NonMatchingValue returns a sensor value, which must not start the event process.
+// the event script compares against the threshold converted to canary_process_characteristic_id;
+// all known conversions are increasing, so values <= EventThreshold stay below it after the conversion.
+func NonMatchingValue() int {
+	return rand.Intn(EventThreshold + 1)
+}
+
+// CheckNoInstanceStarted expects no event process instance event_negative_window after a non-matching value has been published at publishedAt.
+// the caller should run other checks in between; only the remaining part of the window is waited for.
+// false positives are removed from the history to keep the teardown instance count meaningful.
+func (this *Events) CheckNoInstanceStarted(token string, value int, publishedAt time.Time) {
+	if this.deploymentId == "" {
+		return
+	}
+	this.metrics.EventProcessNegativeCheckCount.Inc()
+	time.Sleep(time.Until(publishedAt.Add(this.negativeWindow)))
+	definition, err := this.platform.GetProcessDefinition(token, this.deploymentId)
+	if err != nil {
+		this.metrics.UncategorizedErr.Inc()
+		log.Println("ERROR: GetProcessDefinition()", err)
+		return
+	}
+	instances, err := this.platform.GetProcessInstancesByDefinitionId(token, definition.Id)
+	if err != nil {
+		this.metrics.UncategorizedErr.Inc()
+		log.Println("ERROR: unable to get event process instances", err)
+		return
+	}
+	if len(instances) > 0 {
+		this.metrics.EventProcessFalsePositiveErr.Inc()
+		script, _ := this.getEventScript()
+		log.Printf("ERROR: EventProcessFalsePositiveErr value %v started %v instances of %v \n", value, len(instances), script)
+		this.platform.DeleteFinishedInstanceHistory(token, instances)
+	}
+}
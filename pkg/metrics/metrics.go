@@ -81,6 +81,97 @@ type Metrics struct {
 	EventProcessInstanceDurationMs                         prometheus.Gauge
 	EventProcessPreparedDeploymentErr                      prometheus.Counter
 	EventProcessUnexpectedPreparedDeploymentSelectablesErr prometheus.Counter
+
+	HubLifecycleCount            prometheus.Counter
+	UnexpectedHubMetadataErr     prometheus.Counter
+	UnexpectedHubOnlineStateErr  prometheus.Counter
+	UnexpectedHubOfflineStateErr prometheus.Counter
+
+	DeviceDataIngestionLatencyMs  prometheus.Histogram
+	DeviceDataIngestionTimeoutErr prometheus.Counter
+
+	DeviceDataClockSkewMs       prometheus.Gauge
+	UnexpectedDeviceDataTimeErr prometheus.Counter
+	DeviceDataReorderErr        prometheus.Counter
+
+	DeviceHistoryRequestCount     prometheus.Counter
+	DeviceHistoryRequestLatencyMs prometheus.Gauge
+	DeviceHistoryRequestErr       prometheus.Counter
+	UnexpectedDeviceHistoryErr    prometheus.Counter
+
+	MessageLossCheckCount prometheus.Counter
+	MessageLossRatio      prometheus.Gauge
+	MessageLossDuplicates prometheus.Counter
+	MessageLossOutOfOrder prometheus.Counter
+
+	DeviceTypeDrift                          prometheus.Gauge
+	UnexpectedDeviceTypeUpdatePropagationErr prometheus.Counter
+
+	JanitorDeletedDevices            prometheus.Counter
+	JanitorDeletedDeviceTypes        prometheus.Counter
+	JanitorDeletedHubs               prometheus.Counter
+	JanitorDeletedDeviceGroups       prometheus.Counter
+	JanitorDeletedProcessDeployments prometheus.Counter
+	JanitorErr                       prometheus.Counter
+
+	UnexpectedDeviceRepoAttributeErr prometheus.Counter
+
+	DeviceLifecycleCount         prometheus.Counter
+	UnexpectedDeviceLifecycleErr prometheus.Counter
+
+	DeviceTypeUpdateCheckCount            prometheus.Counter
+	DeviceTypeUpdatePropagationLatencyMs  prometheus.Histogram
+	DeviceTypeUpdatePropagationTimeoutErr prometheus.Counter
+
+	ProcessUnexpectedGroupCommandCountErr prometheus.Counter
+	DeviceGroupCheckCount                 prometheus.Counter
+	UnexpectedDeviceGroupErr              prometheus.Counter
+
+	UnexpectedDeviceDataConversionErr prometheus.Counter
+
+	ProcessUnexpectedCommandConversionErr prometheus.Counter
+
+	OntologyCheckCount prometheus.Counter
+	OntologyCheckErr   prometheus.Counter
+
+	ValidationCheckCount          *prometheus.CounterVec
+	UnexpectedValidationResultErr *prometheus.CounterVec
+
+	ProcessInstanceTimeoutErr      prometheus.Counter
+	EventProcessInstanceTimeoutErr prometheus.Counter
+
+	ProcessIncidentCheckCount             prometheus.Counter
+	ProcessIncidentLatencyMs              prometheus.Gauge
+	ProcessIncidentMissingErr             prometheus.Counter
+	ProcessIncidentNotificationMissingErr prometheus.Counter
+
+	MultiTaskProcessCheckCount     prometheus.Counter
+	UnexpectedMultiTaskCommandErr  prometheus.Counter
+	UnexpectedMultiTaskVariableErr prometheus.Counter
+
+	TimerProcessCheckCount           prometheus.Counter
+	TimerProcessAccuracyMs           prometheus.Gauge
+	UnexpectedTimerProcessCommandErr prometheus.Counter
+
+	ProcessStrayCommandErr     prometheus.Counter
+	ProcessDuplicateCommandErr prometheus.Counter
+	ProcessUnexpectedRunIdErr  prometheus.Counter
+
+	ProcessPlatformRequestCount     *prometheus.CounterVec
+	ProcessPlatformRequestLatencyMs *prometheus.GaugeVec
+	ProcessPlatformRequestErr       *prometheus.CounterVec
+
+	StartParameterProcessCheckCount      prometheus.Counter
+	UnexpectedStartParameterCommandErr   prometheus.Counter
+	ProcessDeploymentUpdateErr           prometheus.Counter
+	UnexpectedProcessDeploymentUpdateErr prometheus.Counter
+
+	EventProcessNegativeCheckCount         prometheus.Counter
+	EventProcessFalsePositiveErr           prometheus.Counter
+	UnexpectedEventProcessInstanceCountErr prometheus.Counter
+
+	EventProcessStartLatencyMs        prometheus.Gauge
+	UnexpectedEventProcessVariableErr prometheus.Counter
 }
 
 func NewMetrics(reg prometheus.Registerer) *Metrics {
@@ -279,6 +370,300 @@ func NewMetrics(reg prometheus.Registerer) *Metrics {
 			Name: "snowflake_canary_event_unexpected_prepared_deployment_selectables_err",
 			Help: "total count of prepared process selectable errors since canary startup",
 		}),
+
+		HubLifecycleCount: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_hub_lifecycle_count",
+			Help: countHelpMsg,
+		}),
+		UnexpectedHubMetadataErr: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_unexpected_hub_metadata_err",
+			Help: "total count of unexpected hub metadata errors since canary startup",
+		}),
+		UnexpectedHubOnlineStateErr: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_unexpected_hub_online_state_err",
+			Help: "total count of unexpected hub online state errors since canary startup",
+		}),
+		UnexpectedHubOfflineStateErr: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_unexpected_hub_offline_state_err",
+			Help: "total count of unexpected hub offline state errors since canary startup",
+		}),
+
+		DeviceDataIngestionLatencyMs: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "snowflake_canary_device_data_ingestion_latency_ms",
+			Help:    "time in ms between the start of the mqtt publish and the first last-value response containing the published value",
+			Buckets: prometheus.ExponentialBuckets(50, 2, 12),
+		}),
+		DeviceDataIngestionTimeoutErr: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_device_data_ingestion_timeout_err",
+			Help: "total count of published values that were not queryable within the ingestion timeout since canary startup",
+		}),
+
+		DeviceDataClockSkewMs: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "snowflake_canary_device_data_clock_skew_ms",
+			Help: "difference in ms between the last value time and the start of the mqtt publish of the canary",
+		}),
+		UnexpectedDeviceDataTimeErr: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_unexpected_device_data_time_err",
+			Help: "total count of last value times outside of the publish to query window since canary startup",
+		}),
+		DeviceDataReorderErr: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_device_data_reorder_err",
+			Help: "total count of last value times older than previously seen last value times since canary startup",
+		}),
+
+		DeviceHistoryRequestCount: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_device_history_request_count",
+			Help: countHelpMsg,
+		}),
+		DeviceHistoryRequestLatencyMs: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "snowflake_canary_device_history_request_latency_ms",
+			Help: "latency of device history request",
+		}),
+		DeviceHistoryRequestErr: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_device_history_request_err",
+			Help: "total count of device history request errors since canary startup",
+		}),
+		UnexpectedDeviceHistoryErr: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_unexpected_device_history_err",
+			Help: "total count of unexpected device history values since canary startup",
+		}),
+
+		MessageLossCheckCount: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_message_loss_check_count",
+			Help: countHelpMsg,
+		}),
+		MessageLossRatio: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "snowflake_canary_message_loss_ratio",
+			Help: "ratio of lost messages in the last sequence-numbered burst",
+		}),
+		MessageLossDuplicates: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_message_loss_duplicates",
+			Help: "total count of duplicated burst messages since canary startup",
+		}),
+		MessageLossOutOfOrder: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_message_loss_out_of_order",
+			Help: "total count of out of order burst messages since canary startup",
+		}),
+
+		DeviceTypeDrift: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "snowflake_canary_device_type_drift",
+			Help: "count of differences between the canary device type and the expected device type spec",
+		}),
+		UnexpectedDeviceTypeUpdatePropagationErr: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_unexpected_device_type_update_propagation_err",
+			Help: "total count of device type updates that did not propagate to the device repository since canary startup",
+		}),
+
+		JanitorDeletedDevices: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_janitor_deleted_devices",
+			Help: "total count of leaked canary devices deleted since canary startup",
+		}),
+		JanitorDeletedDeviceTypes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_janitor_deleted_device_types",
+			Help: "total count of leaked canary device-types deleted since canary startup",
+		}),
+		JanitorDeletedHubs: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_janitor_deleted_hubs",
+			Help: "total count of leaked canary hubs deleted since canary startup",
+		}),
+		JanitorDeletedDeviceGroups: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_janitor_deleted_device_groups",
+			Help: "total count of leaked canary device-groups deleted since canary startup",
+		}),
+		JanitorDeletedProcessDeployments: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_janitor_deleted_process_deployments",
+			Help: "total count of leaked canary process deployments deleted since canary startup",
+		}),
+		JanitorErr: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_janitor_err",
+			Help: "total count of janitor errors since canary startup",
+		}),
+
+		UnexpectedDeviceRepoAttributeErr: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_unexpected_device_repo_attribute_err",
+			Help: "total count of unexpected device-repository attribute sets since canary startup",
+		}),
+
+		DeviceLifecycleCount: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_device_lifecycle_count",
+			Help: countHelpMsg,
+		}),
+		UnexpectedDeviceLifecycleErr: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_unexpected_device_lifecycle_err",
+			Help: "total count of unexpected device lifecycle results since canary startup",
+		}),
+
+		DeviceTypeUpdateCheckCount: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_device_type_update_check_count",
+			Help: countHelpMsg,
+		}),
+		DeviceTypeUpdatePropagationLatencyMs: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "snowflake_canary_device_type_update_propagation_latency_ms",
+			Help:    "latency between a device-type update and the first value queryable in the new shape",
+			Buckets: prometheus.ExponentialBuckets(50, 2, 12),
+		}),
+		DeviceTypeUpdatePropagationTimeoutErr: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_device_type_update_propagation_timeout_err",
+			Help: "total count of device-type updates not effective within the timeout since canary startup",
+		}),
+
+		ProcessUnexpectedGroupCommandCountErr: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_process_unexpected_group_command_count_err",
+			Help: "total count of group processes without received command since canary startup",
+		}),
+		DeviceGroupCheckCount: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_device_group_check_count",
+			Help: countHelpMsg,
+		}),
+		UnexpectedDeviceGroupErr: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_unexpected_device_group_err",
+			Help: "total count of unexpected device group criteria or members since canary startup",
+		}),
+
+		UnexpectedDeviceDataConversionErr: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_unexpected_device_data_conversion_err",
+			Help: "total count of unexpected converted device data values since canary startup",
+		}),
+
+		ProcessUnexpectedCommandConversionErr: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_process_unexpected_command_conversion_err",
+			Help: "total count of command conversion cases without matching command since canary startup",
+		}),
+
+		OntologyCheckCount: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_ontology_check_count",
+			Help: countHelpMsg,
+		}),
+		OntologyCheckErr: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_ontology_check_err",
+			Help: "total count of failed ontology checks since canary startup; canary tests are skipped while the check fails",
+		}),
+
+		ValidationCheckCount: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "snowflake_canary_validation_check_count",
+			Help: countHelpMsg,
+		}, []string{"case"}),
+		UnexpectedValidationResultErr: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "snowflake_canary_unexpected_validation_result_err",
+			Help: "total count of invalid metadata accepted by the validation since canary startup",
+		}, []string{"case"}),
+
+		ProcessInstanceTimeoutErr: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_process_instance_timeout_err",
+			Help: "total count of process instances not finished within process_instance_timeout since canary startup",
+		}),
+		EventProcessInstanceTimeoutErr: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_event_process_instance_timeout_err",
+			Help: "total count of event process instances not finished within process_instance_timeout since canary startup",
+		}),
+
+		ProcessIncidentCheckCount: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_process_incident_check_count",
+			Help: countHelpMsg,
+		}),
+		ProcessIncidentLatencyMs: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "snowflake_canary_process_incident_latency_ms",
+			Help: "time between start of the incident process and its incident in ms",
+		}),
+		ProcessIncidentMissingErr: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_process_incident_missing_err",
+			Help: "total count of incident checks without incident since canary startup",
+		}),
+		ProcessIncidentNotificationMissingErr: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_process_incident_notification_missing_err",
+			Help: "total count of incident checks without incident notification since canary startup",
+		}),
+
+		MultiTaskProcessCheckCount: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_multi_task_process_check_count",
+			Help: countHelpMsg,
+		}),
+		UnexpectedMultiTaskCommandErr: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_unexpected_multi_task_command_err",
+			Help: "total count of unexpected commands of the multi task process since canary startup",
+		}),
+		UnexpectedMultiTaskVariableErr: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_unexpected_multi_task_variable_err",
+			Help: "total count of unexpected final variables of the multi task process since canary startup",
+		}),
+
+		TimerProcessCheckCount: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_timer_process_check_count",
+			Help: countHelpMsg,
+		}),
+		TimerProcessAccuracyMs: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "snowflake_canary_timer_process_accuracy_ms",
+			Help: "delay of the timer process command after the configured timer duration in ms",
+		}),
+		UnexpectedTimerProcessCommandErr: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_unexpected_timer_process_command_err",
+			Help: "total count of timer process commands missing or outside the expected window since canary startup",
+		}),
+
+		ProcessStrayCommandErr: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_process_stray_command_err",
+			Help: "total count of commands not matching any running process check since canary startup",
+		}),
+		ProcessDuplicateCommandErr: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_process_duplicate_command_err",
+			Help: "total count of commands with an already received correlation id since canary startup",
+		}),
+		ProcessUnexpectedRunIdErr: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_process_unexpected_run_id_err",
+			Help: "total count of process instances without the run id of the current canary run since canary startup",
+		}),
+
+		ProcessPlatformRequestCount: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "snowflake_canary_process_platform_request_count",
+			Help: countHelpMsg,
+		}, []string{"operation"}),
+		ProcessPlatformRequestLatencyMs: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "snowflake_canary_process_platform_request_latency_ms",
+			Help: "latency of process platform requests in ms",
+		}, []string{"operation"}),
+		ProcessPlatformRequestErr: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "snowflake_canary_process_platform_request_err",
+			Help: "total count of process platform request errors since canary startup",
+		}, []string{"operation"}),
+
+		StartParameterProcessCheckCount: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_start_parameter_process_check_count",
+			Help: countHelpMsg,
+		}),
+		UnexpectedStartParameterCommandErr: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_unexpected_start_parameter_command_err",
+			Help: "total count of unexpected start parameter process commands since canary startup",
+		}),
+		ProcessDeploymentUpdateErr: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_process_deployment_update_err",
+			Help: "total count of process deployment update errors since canary startup",
+		}),
+		UnexpectedProcessDeploymentUpdateErr: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_unexpected_process_deployment_update_err",
+			Help: "total count of process deployment updates with changed deployment id or unchanged process definition since canary startup",
+		}),
+
+		EventProcessNegativeCheckCount: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_event_process_negative_check_count",
+			Help: countHelpMsg,
+		}),
+		EventProcessFalsePositiveErr: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_event_process_false_positive_err",
+			Help: "total count of event process instances started by values not matching the event script since canary startup",
+		}),
+		UnexpectedEventProcessInstanceCountErr: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_unexpected_event_process_instance_count_err",
+			Help: "total count of event process runs with other than exactly one instance for the matching value since canary startup",
+		}),
+
+		EventProcessStartLatencyMs: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "snowflake_canary_event_process_start_latency_ms",
+			Help: "latency between publishing the matching sensor value and the start of the event process instance in ms",
+		}),
+		UnexpectedEventProcessVariableErr: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snowflake_canary_unexpected_event_process_variable_err",
+			Help: "total count of event process instances with missing or unexpected value variable since canary startup",
+		}),
 	}
 
 	reg.MustRegister(m.AuthCount)
@@ -342,5 +727,96 @@ func NewMetrics(reg prometheus.Registerer) *Metrics {
 	reg.MustRegister(m.EventProcessPreparedDeploymentErr)
 	reg.MustRegister(m.EventProcessUnexpectedPreparedDeploymentSelectablesErr)
 
+	reg.MustRegister(m.HubLifecycleCount)
+	reg.MustRegister(m.UnexpectedHubMetadataErr)
+	reg.MustRegister(m.UnexpectedHubOnlineStateErr)
+	reg.MustRegister(m.UnexpectedHubOfflineStateErr)
+
+	reg.MustRegister(m.DeviceDataIngestionLatencyMs)
+	reg.MustRegister(m.DeviceDataIngestionTimeoutErr)
+
+	reg.MustRegister(m.DeviceDataClockSkewMs)
+	reg.MustRegister(m.UnexpectedDeviceDataTimeErr)
+	reg.MustRegister(m.DeviceDataReorderErr)
+
+	reg.MustRegister(m.DeviceHistoryRequestCount)
+	reg.MustRegister(m.DeviceHistoryRequestLatencyMs)
+	reg.MustRegister(m.DeviceHistoryRequestErr)
+	reg.MustRegister(m.UnexpectedDeviceHistoryErr)
+
+	reg.MustRegister(m.MessageLossCheckCount)
+	reg.MustRegister(m.MessageLossRatio)
+	reg.MustRegister(m.MessageLossDuplicates)
+	reg.MustRegister(m.MessageLossOutOfOrder)
+
+	reg.MustRegister(m.DeviceTypeDrift)
+	reg.MustRegister(m.UnexpectedDeviceTypeUpdatePropagationErr)
+
+	reg.MustRegister(m.JanitorDeletedDevices)
+	reg.MustRegister(m.JanitorDeletedDeviceTypes)
+	reg.MustRegister(m.JanitorDeletedHubs)
+	reg.MustRegister(m.JanitorDeletedDeviceGroups)
+	reg.MustRegister(m.JanitorDeletedProcessDeployments)
+	reg.MustRegister(m.JanitorErr)
+
+	reg.MustRegister(m.UnexpectedDeviceRepoAttributeErr)
+
+	reg.MustRegister(m.DeviceLifecycleCount)
+	reg.MustRegister(m.UnexpectedDeviceLifecycleErr)
+
+	reg.MustRegister(m.DeviceTypeUpdateCheckCount)
+	reg.MustRegister(m.DeviceTypeUpdatePropagationLatencyMs)
+	reg.MustRegister(m.DeviceTypeUpdatePropagationTimeoutErr)
+
+	reg.MustRegister(m.ProcessUnexpectedGroupCommandCountErr)
+	reg.MustRegister(m.DeviceGroupCheckCount)
+	reg.MustRegister(m.UnexpectedDeviceGroupErr)
+
+	reg.MustRegister(m.UnexpectedDeviceDataConversionErr)
+
+	reg.MustRegister(m.ProcessUnexpectedCommandConversionErr)
+
+	reg.MustRegister(m.OntologyCheckCount)
+	reg.MustRegister(m.OntologyCheckErr)
+
+	reg.MustRegister(m.ValidationCheckCount)
+	reg.MustRegister(m.UnexpectedValidationResultErr)
+
+	reg.MustRegister(m.ProcessInstanceTimeoutErr)
+	reg.MustRegister(m.EventProcessInstanceTimeoutErr)
+
+	reg.MustRegister(m.ProcessIncidentCheckCount)
+	reg.MustRegister(m.ProcessIncidentLatencyMs)
+	reg.MustRegister(m.ProcessIncidentMissingErr)
+	reg.MustRegister(m.ProcessIncidentNotificationMissingErr)
+
+	reg.MustRegister(m.MultiTaskProcessCheckCount)
+	reg.MustRegister(m.UnexpectedMultiTaskCommandErr)
+	reg.MustRegister(m.UnexpectedMultiTaskVariableErr)
+
+	reg.MustRegister(m.TimerProcessCheckCount)
+	reg.MustRegister(m.TimerProcessAccuracyMs)
+	reg.MustRegister(m.UnexpectedTimerProcessCommandErr)
+
+	reg.MustRegister(m.ProcessStrayCommandErr)
+	reg.MustRegister(m.ProcessDuplicateCommandErr)
+	reg.MustRegister(m.ProcessUnexpectedRunIdErr)
+
+	reg.MustRegister(m.ProcessPlatformRequestCount)
+	reg.MustRegister(m.ProcessPlatformRequestLatencyMs)
+	reg.MustRegister(m.ProcessPlatformRequestErr)
+
+	reg.MustRegister(m.StartParameterProcessCheckCount)
+	reg.MustRegister(m.UnexpectedStartParameterCommandErr)
+	reg.MustRegister(m.ProcessDeploymentUpdateErr)
+	reg.MustRegister(m.UnexpectedProcessDeploymentUpdateErr)
+
+	reg.MustRegister(m.EventProcessNegativeCheckCount)
+	reg.MustRegister(m.EventProcessFalsePositiveErr)
+	reg.MustRegister(m.UnexpectedEventProcessInstanceCountErr)
+
+	reg.MustRegister(m.EventProcessStartLatencyMs)
+	reg.MustRegister(m.UnexpectedEventProcessVariableErr)
+
 	return m
 }
@@ -0,0 +1,144 @@
+/*
+ * Copyright (c) 2023 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package process
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"sync"
+	"time"
+)
+
+const ExpectedCanaryConversionDeploymentName = "snowflake_canary_conversion_process"
+
+// CommandConversionCase sends Input in CharacteristicId; the device expects Expected in the characteristic of the cmd service (Degree Celsius int).
+// expected values have to be unique, because received commands are matched to cases by value.
+type CommandConversionCase struct {
+	Name             string
+	CharacteristicId string
+	Input            string
+	Expected         string
+}
+
+// GetCommandConversionCases returns the cases of the command conversion check; cases of unconfigured characteristics are skipped
+func (this *Process) GetCommandConversionCases() (result []CommandConversionCase) {
+	result = append(result, CommandConversionCase{Name: "celsius", CharacteristicId: this.config.CanaryProcessCharacteristicId, Input: "20", Expected: "20"})
+	if this.config.CanarySensorKelvinCharacteristicId != "" {
+		result = append(result,
+			CommandConversionCase{Name: "kelvin", CharacteristicId: this.config.CanarySensorKelvinCharacteristicId, Input: "300.15", Expected: "27"},
+			CommandConversionCase{Name: "kelvin_below_zero", CharacteristicId: this.config.CanarySensorKelvinCharacteristicId, Input: "263.15", Expected: "-10"},
+		)
+	}
+	if this.config.CanarySensorFahrenheitCharacteristicId != "" {
+		result = append(result,
+			CommandConversionCase{Name: "fahrenheit", CharacteristicId: this.config.CanarySensorFahrenheitCharacteristicId, Input: "86", Expected: "30"},
+			CommandConversionCase{Name: "fahrenheit_below_zero", CharacteristicId: this.config.CanarySensorFahrenheitCharacteristicId, Input: "-4", Expected: "-20"},
+		)
+	}
+	return result
+}
+
+type conversionState struct {
+	mux        sync.Mutex
+	cases      []CommandConversionCase
+	received   map[string]int
+	unexpected []map[string]string
+}
+
+func (this *conversionState) reset(cases []CommandConversionCase) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.cases = cases
+	this.received = map[string]int{}
+	this.unexpected = nil
+}
+
+// notify returns true if the payload matches a conversion case.
+// while cases are active, other payloads are remembered for the failure report.
+func (this *conversionState) notify(payload map[string]string) bool {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	if len(this.cases) == 0 {
+		return false
+	}
+	for _, c := range this.cases {
+		if reflect.DeepEqual(payload, getExpectedCommandPayload(c.Expected)) {
+			this.received[c.Name] = this.received[c.Name] + 1
+			return true
+		}
+	}
+	this.unexpected = append(this.unexpected, payload)
+	return false
+}
+
+// ConversionProcessStartup deploys and starts one process per command conversion case
+func (this *Process) ConversionProcessStartup(token string, info DeviceInfo) error {
+	cases := this.GetCommandConversionCases()
+	this.conversions.reset(cases)
+	err := this.platform.DeleteDeploymentsByName(token, ExpectedCanaryConversionDeploymentName)
+	if err != nil {
+		return err
+	}
+	serviceId, err := this.getCmdServiceId(token, info)
+	if err != nil {
+		return err
+	}
+	deploymentIds := []string{}
+	for _, c := range cases {
+		deplId, err := this.DeployProcessVariant(token, DeploymentVariant{
+			Name:             ExpectedCanaryConversionDeploymentName,
+			ProcessId:        "snowflake_canary_conversion_command_" + c.Name,
+			DeviceId:         info.Id,
+			ServiceId:        serviceId,
+			CharacteristicId: c.CharacteristicId,
+			Input:            c.Input,
+		})
+		if err != nil {
+			this.metrics.ProcessDeploymentErr.Inc()
+			log.Println("ERROR: ProcessDeploymentErr", c.Name, err)
+			return err
+		}
+		deploymentIds = append(deploymentIds, deplId)
+	}
+
+	time.Sleep(this.getChangeGuaranteeDuration())
+
+	for _, deplId := range deploymentIds {
+		err = this.platform.Start(token, deplId, nil)
+		if err != nil {
+			this.metrics.ProcessStartErr.Inc()
+			log.Println("ERROR: ProcessStartErr", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// ConversionProcessTeardown reports every case without a matching command and removes the deployments
+func (this *Process) ConversionProcessTeardown(token string) error {
+	this.conversions.mux.Lock()
+	for _, c := range this.conversions.cases {
+		if this.conversions.received[c.Name] == 0 {
+			this.metrics.ProcessUnexpectedCommandConversionErr.Inc()
+			log.Printf("ERROR: ProcessUnexpectedCommandConversionErr case %v: %v in %v; expected %v; no matching command received; unmatched commands: %v\n", c.Name, c.Input, c.CharacteristicId, c.Expected, fmt.Sprint(this.conversions.unexpected))
+		}
+	}
+	this.conversions.mux.Unlock()
+	this.conversions.reset(nil)
+	return this.platform.DeleteDeploymentsByName(token, ExpectedCanaryConversionDeploymentName)
+}
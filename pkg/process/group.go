@@ -0,0 +1,118 @@
+/*
+ * Copyright (c) 2023 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package process
+
+import (
+	"errors"
+	"github.com/SENERGY-Platform/snowflake-canary/pkg/processplatform"
+	"log"
+	"strconv"
+	"time"
+)
+
+const ExpectedCanaryGroupDeploymentName = "snowflake_canary_group_process"
+
+// GroupCommandValue distinguishes commands of the group process from commands of the device process
+const GroupCommandValue = 43
+
+// GroupProcessStartup deploys and starts a process with a task targeting the device group
+func (this *Process) GroupProcessStartup(token string, groupId string) error {
+	this.receivedGroupCommands.Store(0)
+	this.groupDeploymentId = ""
+	err := this.platform.DeleteDeploymentsByName(token, ExpectedCanaryGroupDeploymentName)
+	if err != nil {
+		return err
+	}
+
+	//check prepared deployment
+	preparedDepl, err := this.PrepareProcessDeployment(token)
+	if err != nil {
+		this.metrics.ProcessPreparedDeploymentErr.Inc()
+		log.Println("ERROR: ProcessPreparedDeploymentErr", err)
+	} else {
+		foundGroup := false
+		for _, e := range preparedDepl.Elements {
+			if e.BpmnId == CommandTaskBpmnId && e.Task != nil {
+				for _, o := range e.Task.Selection.SelectionOptions {
+					if o.DeviceGroup != nil && o.DeviceGroup.Id == groupId {
+						foundGroup = true
+					}
+				}
+			}
+		}
+		if !foundGroup {
+			this.metrics.ProcessUnexpectedPreparedDeploymentSelectablesErr.Inc()
+			log.Println("ERROR: ProcessUnexpectedPreparedDeploymentSelectablesErr !foundGroup", groupId)
+		}
+	}
+
+	deplId, err := this.DeployProcessVariant(token, DeploymentVariant{
+		Name:          ExpectedCanaryGroupDeploymentName,
+		ProcessId:     "snowflake_canary_group_command",
+		DeviceGroupId: groupId,
+		Input:         strconv.Itoa(GroupCommandValue),
+	})
+	if err != nil {
+		this.metrics.ProcessDeploymentErr.Inc()
+		log.Println("ERROR: ProcessDeploymentErr", err)
+		return err
+	}
+	this.groupDeploymentId = deplId
+
+	time.Sleep(this.getChangeGuaranteeDuration())
+
+	err = this.platform.Start(token, deplId, nil)
+	if err != nil {
+		this.metrics.ProcessStartErr.Inc()
+		log.Println("ERROR: ProcessStartErr", err)
+		return err
+	}
+	return nil
+}
+
+// GroupProcessTeardown expects exactly one command, because the group contains only the canary device
+func (this *Process) GroupProcessTeardown(token string) error {
+	if this.groupDeploymentId != "" {
+		instances, err := this.platform.WaitForFinishedInstances(token, this.groupDeploymentId, 1)
+		switch {
+		case errors.Is(err, processplatform.ErrInstanceTimeout):
+			this.metrics.ProcessInstanceTimeoutErr.Inc()
+			log.Printf("ERROR: ProcessInstanceTimeoutErr group %#v \n", instances)
+		case err != nil:
+			this.metrics.UncategorizedErr.Inc()
+			log.Println("ERROR: unable to get group process instances", err)
+		case len(instances) != 1:
+			this.metrics.UncategorizedErr.Inc()
+			log.Println("ERROR: unexpected group process instance list count", len(instances))
+		case instances[0].State != "COMPLETED":
+			this.metrics.UnexpectedProcessInstanceStateErr.Inc()
+			log.Printf("ERROR: UnexpectedProcessInstanceStateErr %#v \n", instances)
+		}
+		this.platform.DeleteFinishedInstanceHistory(token, instances)
+	}
+
+	err := this.platform.DeleteDeploymentsByName(token, ExpectedCanaryGroupDeploymentName)
+	if err != nil {
+		return err
+	}
+
+	if this.receivedGroupCommands.Load() != 1 {
+		this.metrics.ProcessUnexpectedGroupCommandCountErr.Inc()
+		log.Println("ERROR: ProcessUnexpectedGroupCommandCountErr", this.receivedGroupCommands.Load())
+	}
+	return nil
+}
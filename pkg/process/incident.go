@@ -0,0 +1,171 @@
+/*
+ * Copyright (c) 2023 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package process
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const ExpectedCanaryIncidentDeploymentName = "snowflake_canary_incident_process"
+
+// IncidentCommandValue marks the command of the incident process, which the canary device answers with an error
+const IncidentCommandValue = 44
+
+// ErrIncidentCommand is returned by NotifyCommand for commands, which have to be answered with an error
+var ErrIncidentCommand = errors.New("incident check command")
+
+// IncidentProcessStartup deploys and starts a process without task retries, whose command is answered with an error
+func (this *Process) IncidentProcessStartup(token string, info DeviceInfo) error {
+	this.metrics.ProcessIncidentCheckCount.Inc()
+	this.incidentDeploymentId = ""
+	this.receivedIncidentCommands.Store(0)
+	err := this.platform.DeleteDeploymentsByName(token, ExpectedCanaryIncidentDeploymentName)
+	if err != nil {
+		return err
+	}
+	serviceId, err := this.getCmdServiceId(token, info)
+	if err != nil {
+		return err
+	}
+	deplId, err := this.DeployProcessVariant(token, DeploymentVariant{
+		Name:      ExpectedCanaryIncidentDeploymentName,
+		ProcessId: "snowflake_canary_incident_command",
+		DeviceId:  info.Id,
+		ServiceId: serviceId,
+		Input:     strconv.Itoa(IncidentCommandValue),
+		NoRetries: true,
+	})
+	if err != nil {
+		this.metrics.ProcessDeploymentErr.Inc()
+		log.Println("ERROR: ProcessDeploymentErr incident", err)
+		return err
+	}
+	this.incidentDeploymentId = deplId
+
+	time.Sleep(this.getChangeGuaranteeDuration())
+
+	this.incidentProcessStartedAt = time.Now()
+	err = this.platform.Start(token, deplId, nil)
+	if err != nil {
+		this.metrics.ProcessStartErr.Inc()
+		log.Println("ERROR: ProcessStartErr", err)
+		return err
+	}
+	return nil
+}
+
+// IncidentProcessTeardown waits for the incident and its notification, exports the time to incident and removes incident, notification and deployment
+func (this *Process) IncidentProcessTeardown(token string) error {
+	if this.incidentDeploymentId != "" {
+		this.checkIncident(token)
+	}
+	return this.platform.DeleteDeploymentsByName(token, ExpectedCanaryIncidentDeploymentName)
+}
+
+func (this *Process) checkIncident(token string) {
+	if this.receivedIncidentCommands.Load() == 0 {
+		log.Println("WARNING: incident command not received; incident may only be created by command timeout")
+	}
+	definition, err := this.platform.GetProcessDefinition(token, this.incidentDeploymentId)
+	if err != nil {
+		this.metrics.UncategorizedErr.Inc()
+		log.Println("ERROR: GetProcessDefinition()", err)
+		return
+	}
+	incidents := []Incident{}
+	notificationIds := []string{}
+	deadline := time.Now().Add(this.platform.InstanceTimeout())
+	for {
+		if len(incidents) == 0 {
+			incidents, err = this.platform.GetIncidentsByDefinitionId(token, definition.Id)
+			if err != nil {
+				this.metrics.UncategorizedErr.Inc()
+				log.Println("ERROR: GetIncidentsByDefinitionId()", err)
+			}
+		}
+		if len(notificationIds) == 0 {
+			notificationIds = this.findIncidentNotifications(token)
+		}
+		if (len(incidents) > 0 && len(notificationIds) > 0) || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(this.platform.InstancePollInterval())
+	}
+
+	if len(incidents) == 0 {
+		this.metrics.ProcessIncidentMissingErr.Inc()
+		log.Println("ERROR: ProcessIncidentMissingErr no incident for", ExpectedCanaryIncidentDeploymentName, definition.Id)
+	} else {
+		this.metrics.ProcessIncidentLatencyMs.Set(float64(incidents[0].Time.Sub(this.incidentProcessStartedAt).Milliseconds()))
+	}
+	if len(notificationIds) == 0 {
+		this.metrics.ProcessIncidentNotificationMissingErr.Inc()
+		log.Println("ERROR: ProcessIncidentNotificationMissingErr no notification for", ExpectedCanaryIncidentDeploymentName)
+	}
+
+	//cleanup
+	for _, incident := range incidents {
+		err = this.platform.DeleteIncident(token, incident.Id)
+		if err != nil {
+			this.metrics.UncategorizedErr.Inc()
+			log.Println("ERROR: DeleteIncident()", err)
+		}
+	}
+	if len(notificationIds) > 0 {
+		this.metrics.NotificationDeleteCount.Inc()
+		start := time.Now()
+		err = this.platform.DeleteNotifications(token, notificationIds)
+		this.metrics.NotificationDeleteLatencyMs.Set(float64(time.Since(start).Milliseconds()))
+		if err != nil {
+			this.metrics.NotificationDeleteErr.Inc()
+			log.Println("ERROR: DeleteNotifications()", err)
+		}
+	}
+	instances, err := this.platform.GetProcessInstancesByDefinitionId(token, definition.Id)
+	if err != nil {
+		this.metrics.UncategorizedErr.Inc()
+		log.Println("ERROR: GetProcessInstancesByDefinitionId()", err)
+		return
+	}
+	this.platform.DeleteFinishedInstanceHistory(token, instances)
+}
+
+// findIncidentNotifications returns the ids of notifications, which mention the incident deployment and have been created after the process start
+func (this *Process) findIncidentNotifications(token string) (ids []string) {
+	this.metrics.NotificationReadCount.Inc()
+	start := time.Now()
+	notifications, err := this.platform.ListNotifications(token)
+	this.metrics.NotificationReadLatencyMs.Set(float64(time.Since(start).Milliseconds()))
+	if err != nil {
+		this.metrics.NotificationReadErr.Inc()
+		log.Println("ERROR: ListNotifications()", err)
+		return nil
+	}
+	for _, notification := range notifications {
+		if notification.CreatedAt.Before(this.incidentProcessStartedAt) {
+			continue
+		}
+		if strings.Contains(notification.Title, ExpectedCanaryIncidentDeploymentName) || strings.Contains(notification.Message, ExpectedCanaryIncidentDeploymentName) {
+			ids = append(ids, notification.Id)
+		}
+	}
+	return ids
+}
@@ -16,50 +16,20 @@
 
 package process
 
-type ProcessInstance struct {
-	Id                    string `json:"id"`
-	ProcessDefinitionName string `json:"processDefinitionName"`
-	StartTime             string `json:"startTime"`
-	EndTime               string `json:"endTime"`
-	DurationInMillis      int    `json:"durationInMillis"`
-	State                 string `json:"state"`
-}
+import (
+	"github.com/SENERGY-Platform/snowflake-canary/pkg/processplatform"
+)
 
-type PreparedDeployment struct {
-	Id       string    `json:"id"`
-	Name     string    `json:"name"`
-	Elements []Element `json:"elements"`
-}
+type ProcessInstance = processplatform.ProcessInstance
 
-type Element struct {
-	BpmnId string `json:"bpmn_id"`
-	Task   *Task  `json:"task"`
-}
+type ProcessDefinition = processplatform.ProcessDefinition
 
-type Task struct {
-	Selection Selection `json:"selection"`
-}
+type PreparedDeployment = processplatform.PreparedDeployment
 
-type Selection struct {
-	SelectionOptions []SelectionOption `json:"selection_options"`
-}
+type Incident = processplatform.Incident
 
-type SelectionOption struct {
-	Device   *Device   `json:"device"`
-	Services []Service `json:"services"`
-}
+type HistoricVariable = processplatform.HistoricVariable
 
-type Device struct {
-	Id   string `json:"id"`
-	Name string `json:"name"`
-}
+type NotificationList = processplatform.NotificationList
 
-type DeviceGroup struct {
-	Id   string `json:"id"`
-	Name string `json:"name"`
-}
-
-type Service struct {
-	Id   string `json:"id"`
-	Name string `json:"name"`
-}
+type Notification = processplatform.Notification
@@ -0,0 +1,296 @@
+/*
+ * Copyright (c) 2023 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package process
+
+import (
+	"errors"
+	"github.com/SENERGY-Platform/snowflake-canary/pkg/bpmn"
+	"github.com/SENERGY-Platform/snowflake-canary/pkg/processplatform"
+	"log"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const ExpectedCanaryMultiTaskDeploymentName = "snowflake_canary_multi_task_process"
+
+// command values of the multi task process; the combined values are computed by the script task
+const (
+	MultiTaskHighValueA    = 45
+	MultiTaskHighValueB    = 46
+	MultiTaskLowValue      = 47
+	MultiTaskHighCombined  = MultiTaskHighValueA + MultiTaskHighValueB
+	MultiTaskLowCombined   = 2 * MultiTaskLowValue
+	MultiTaskResultVarName = "canary_result"
+	MultiTaskSensorVarName = "temperature"
+)
+
+const multiTaskReadBpmnId = "Task_read_temperature"
+const multiTaskHighABpmnId = "Task_high_a"
+const multiTaskHighBBpmnId = "Task_high_b"
+const multiTaskLowBpmnId = "Task_low"
+const multiTaskFinalBpmnId = "Task_final"
+
+const multiTaskScript = `var a = execution.getVariable("cmd_high_a");
+var b = execution.getVariable("cmd_high_b");
+var low = execution.getVariable("cmd_low");
+if (low != null) {
+    execution.setVariable("canary_result", 2 * Number(low));
+} else {
+    execution.setVariable("canary_result", Number(a) + Number(b));
+}`
+
+// getMultiTaskProcessBuilder creates a process, which reads the canary sensor and branches on the value:
+// values >= 0 send two commands in parallel, other values send one command.
+// a script task combines the sent values; the result is sent by a final command.
+func (this *Process) getMultiTaskProcessBuilder() (*bpmn.Builder, error) {
+	commandTask, err := this.resolveTaskInfo(bpmn.TaskInfo{
+		FunctionId:       this.config.CanaryCmdFunctionId,
+		DeviceClassId:    this.config.CanaryDeviceClassId,
+		CharacteristicId: this.config.CanaryProcessCharacteristicId,
+		Retries:          3,
+	})
+	if err != nil {
+		return nil, err
+	}
+	command := func(variable string) bpmn.TaskInfo {
+		task := commandTask
+		task.Outputs = map[string]string{variable: "${inputs}"}
+		return task
+	}
+	readTask, err := this.resolveTaskInfo(bpmn.TaskInfo{
+		FunctionId:       this.config.CanarySensorFunctionId,
+		AspectId:         this.config.CanarySensorAspectId,
+		CharacteristicId: this.config.CanaryProcessCharacteristicId,
+		Retries:          3,
+		Measuring:        true,
+		PreferEvent:      true,
+		Outputs:          map[string]string{MultiTaskSensorVarName: "${outputs}"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	builder := bpmn.NewBuilder("snowflake_canary_multi_task")
+	start := builder.StartEvent("StartEvent_1")
+	read := builder.ServiceTask(multiTaskReadBpmnId, "Read Temperature", readTask)
+	branch := builder.ExclusiveGateway("Gateway_branch")
+	fork := builder.ParallelGateway("Gateway_fork")
+	highA := builder.ServiceTask(multiTaskHighABpmnId, "Set Temperature A", command("cmd_high_a"))
+	highB := builder.ServiceTask(multiTaskHighBBpmnId, "Set Temperature B", command("cmd_high_b"))
+	join := builder.ParallelGateway("Gateway_join")
+	low := builder.ServiceTask(multiTaskLowBpmnId, "Set Temperature Low", command("cmd_low"))
+	merge := builder.ExclusiveGateway("Gateway_merge")
+	combine := builder.ScriptTask("Task_combine", "Combine Commands", multiTaskScript)
+	final := builder.ServiceTask(multiTaskFinalBpmnId, "Set Combined Temperature", command("cmd_final"))
+	end := builder.EndEvent("EndEvent_1")
+
+	builder.Chain(start, read, branch)
+	builder.ConditionalFlow(branch, fork, "${"+MultiTaskSensorVarName+" >= 0}")
+	builder.DefaultFlow(branch, low)
+	builder.Chain(fork, highA, join)
+	builder.Chain(fork, highB, join)
+	builder.Chain(join, merge)
+	builder.Chain(low, merge)
+	builder.Chain(merge, combine, final, end)
+	return builder, nil
+}
+
+func (this *Process) getMultiTaskDeploymentMessage(deviceId string, cmdServiceId string, sensorServiceId string) (deployment bpmn.Deployment, err error) {
+	builder, err := this.getMultiTaskProcessBuilder()
+	if err != nil {
+		return deployment, err
+	}
+	deployment, err = builder.Deployment(ExpectedCanaryMultiTaskDeploymentName)
+	if err != nil {
+		return deployment, err
+	}
+	inputs := map[string]string{
+		multiTaskHighABpmnId: strconv.Itoa(MultiTaskHighValueA),
+		multiTaskHighBBpmnId: strconv.Itoa(MultiTaskHighValueB),
+		multiTaskLowBpmnId:   strconv.Itoa(MultiTaskLowValue),
+		multiTaskFinalBpmnId: "${" + MultiTaskResultVarName + "}",
+	}
+	for i, element := range deployment.Elements {
+		if element.Task == nil {
+			continue
+		}
+		if element.BpmnId == multiTaskReadBpmnId {
+			deployment.Elements[i].Task.Selection.SelectDevice(deviceId, sensorServiceId)
+			continue
+		}
+		input, ok := inputs[element.BpmnId]
+		if !ok {
+			return deployment, errors.New("unexpected multi task deployment element " + element.BpmnId)
+		}
+		deployment.Elements[i].Task.Parameter["inputs"] = input
+		deployment.Elements[i].Task.Selection.SelectDevice(deviceId, cmdServiceId)
+	}
+	return deployment, nil
+}
+
+type multiTaskState struct {
+	mux      sync.Mutex
+	active   bool
+	received []int
+}
+
+func (this *multiTaskState) reset(active bool) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.active = active
+	this.received = nil
+}
+
+// notify returns true and remembers the value if the payload is a command of the multi task process
+func (this *multiTaskState) notify(payload map[string]string) bool {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	if !this.active {
+		return false
+	}
+	for _, value := range []int{MultiTaskHighValueA, MultiTaskHighValueB, MultiTaskLowValue, MultiTaskHighCombined, MultiTaskLowCombined} {
+		if reflect.DeepEqual(payload, getExpectedCommandPayload(value)) {
+			this.received = append(this.received, value)
+			return true
+		}
+	}
+	return false
+}
+
+func (this *multiTaskState) get() []int {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	return append([]int{}, this.received...)
+}
+
+// MultiTaskProcessStartup deploys and starts the multi task process; sensorValue has to be the queryable last value of the canary device
+func (this *Process) MultiTaskProcessStartup(token string, info DeviceInfo, sensorValue float64) error {
+	this.metrics.MultiTaskProcessCheckCount.Inc()
+	this.multiTaskDeploymentId = ""
+	this.multiTaskSensorValue = sensorValue
+	this.multiTask.reset(true)
+	err := this.platform.DeleteDeploymentsByName(token, ExpectedCanaryMultiTaskDeploymentName)
+	if err != nil {
+		return err
+	}
+	cmdServiceId, err := this.getCmdServiceId(token, info)
+	if err != nil {
+		return err
+	}
+	sensorServiceId, err := this.getSensorServiceId(token, info)
+	if err != nil {
+		return err
+	}
+	deployment, err := this.getMultiTaskDeploymentMessage(info.Id, cmdServiceId, sensorServiceId)
+	if err != nil {
+		this.metrics.ProcessDeploymentErr.Inc()
+		log.Println("ERROR: ProcessDeploymentErr multi task", err)
+		return err
+	}
+	deplId, err := this.platform.Deploy(token, deployment)
+	if err != nil {
+		this.metrics.ProcessDeploymentErr.Inc()
+		log.Println("ERROR: ProcessDeploymentErr multi task", err)
+		return err
+	}
+	this.multiTaskDeploymentId = deplId
+
+	time.Sleep(this.getChangeGuaranteeDuration())
+
+	err = this.platform.Start(token, deplId, nil)
+	if err != nil {
+		this.metrics.ProcessStartErr.Inc()
+		log.Println("ERROR: ProcessStartErr", err)
+		return err
+	}
+	return nil
+}
+
+// MultiTaskProcessTeardown checks the branch, order and values of the received commands and the final process variables
+func (this *Process) MultiTaskProcessTeardown(token string) error {
+	if this.multiTaskDeploymentId != "" {
+		this.checkMultiTaskProcess(token)
+	}
+	this.multiTask.reset(false)
+	return this.platform.DeleteDeploymentsByName(token, ExpectedCanaryMultiTaskDeploymentName)
+}
+
+func (this *Process) checkMultiTaskProcess(token string) {
+	instances, err := this.platform.WaitForFinishedInstances(token, this.multiTaskDeploymentId, 1)
+	defer this.platform.DeleteFinishedInstanceHistory(token, instances)
+	switch {
+	case errors.Is(err, processplatform.ErrInstanceTimeout):
+		this.metrics.ProcessInstanceTimeoutErr.Inc()
+		log.Printf("ERROR: ProcessInstanceTimeoutErr multi task %#v \n", instances)
+		return
+	case err != nil:
+		this.metrics.UncategorizedErr.Inc()
+		log.Println("ERROR: unable to get multi task process instances", err)
+		return
+	case len(instances) != 1:
+		this.metrics.UncategorizedErr.Inc()
+		log.Println("ERROR: unexpected multi task process instance list count", len(instances))
+		return
+	case instances[0].State != "COMPLETED":
+		this.metrics.UnexpectedProcessInstanceStateErr.Inc()
+		log.Printf("ERROR: UnexpectedProcessInstanceStateErr %#v \n", instances)
+		return
+	}
+
+	received := this.multiTask.get()
+	expectedResult := MultiTaskLowCombined
+	if this.multiTaskSensorValue >= 0 {
+		expectedResult = MultiTaskHighCombined
+		if len(received) != 3 || !reflect.DeepEqual(sortedInts(received[:2]), []int{MultiTaskHighValueA, MultiTaskHighValueB}) || received[2] != MultiTaskHighCombined {
+			this.metrics.UnexpectedMultiTaskCommandErr.Inc()
+			log.Printf("ERROR: UnexpectedMultiTaskCommandErr sensor value %v: received %v, expected %v and %v in any order, followed by %v\n", this.multiTaskSensorValue, received, MultiTaskHighValueA, MultiTaskHighValueB, MultiTaskHighCombined)
+		}
+	} else if !reflect.DeepEqual(received, []int{MultiTaskLowValue, MultiTaskLowCombined}) {
+		this.metrics.UnexpectedMultiTaskCommandErr.Inc()
+		log.Printf("ERROR: UnexpectedMultiTaskCommandErr sensor value %v: received %v, expected %v\n", this.multiTaskSensorValue, received, []int{MultiTaskLowValue, MultiTaskLowCombined})
+	}
+
+	variables, err := this.platform.GetProcessInstanceHistoryVariables(token, instances[0].Id)
+	if err != nil {
+		this.metrics.UncategorizedErr.Inc()
+		log.Println("ERROR: GetProcessInstanceHistoryVariables()", err)
+		return
+	}
+	values := map[string]HistoricVariable{}
+	for _, variable := range variables {
+		values[variable.Name] = variable
+	}
+	result, err := values[MultiTaskResultVarName].Float()
+	if err != nil || result != float64(expectedResult) {
+		this.metrics.UnexpectedMultiTaskVariableErr.Inc()
+		log.Printf("ERROR: UnexpectedMultiTaskVariableErr %v = %#v, expected %v; %v\n", MultiTaskResultVarName, values[MultiTaskResultVarName].Value, expectedResult, err)
+	}
+	sensor, err := values[MultiTaskSensorVarName].Float()
+	if err != nil || math.Abs(sensor-this.multiTaskSensorValue) > math.Abs(this.multiTaskSensorValue)*1e-9 {
+		this.metrics.UnexpectedMultiTaskVariableErr.Inc()
+		log.Printf("ERROR: UnexpectedMultiTaskVariableErr %v = %#v, expected %v; %v\n", MultiTaskSensorVarName, values[MultiTaskSensorVarName].Value, this.multiTaskSensorValue, err)
+	}
+}
+
+func sortedInts(values []int) []int {
+	result := append([]int{}, values...)
+	sort.Ints(result)
+	return result
+}
@@ -24,28 +24,57 @@ import (
 	"github.com/SENERGY-Platform/snowflake-canary/pkg/configuration"
 	"github.com/SENERGY-Platform/snowflake-canary/pkg/devicemetadata"
 	"github.com/SENERGY-Platform/snowflake-canary/pkg/metrics"
+	"github.com/SENERGY-Platform/snowflake-canary/pkg/processplatform"
 	"log"
 	"reflect"
+	"strconv"
 	"sync/atomic"
 	"time"
 )
 
 type Process struct {
-	config               configuration.Config
-	devicerepo           devicerepo.Interface
-	guaranteeChangeAfter time.Duration
-	receivedCommands     atomic.Int64
-	metrics              *metrics.Metrics
+	config                configuration.Config
+	devicerepo            devicerepo.Interface
+	guaranteeChangeAfter  time.Duration
+	platform              *processplatform.Client
+	deploymentId          string
+	groupDeploymentId     string
+	run                   commandRun
+	receivedGroupCommands atomic.Int64
+	conversions           conversionState
+	metrics               *metrics.Metrics
+
+	incidentDeploymentId     string
+	incidentProcessStartedAt time.Time
+	receivedIncidentCommands atomic.Int64
+
+	multiTaskDeploymentId string
+	multiTaskSensorValue  float64
+	multiTask             multiTaskState
+
+	timerDuration          time.Duration
+	timerTolerance         time.Duration
+	timerDeploymentId      string
+	timerProcessStartedAt  time.Time
+	timerCommandReceivedAt atomic.Int64 //unix nano of the first timer command
+
+	startParameterDeploymentId string
+	startParameterDeviceInfo   DeviceInfo
+	startParameterServiceId    string
+	startParameter             startParameterState
 }
 
 type DeviceInfo = devicemetadata.DeviceInfo
 
-func New(config configuration.Config, devicerepo devicerepo.Interface, metrics *metrics.Metrics, guaranteeChangeAfter time.Duration) *Process {
+func New(config configuration.Config, devicerepo devicerepo.Interface, metrics *metrics.Metrics, platform *processplatform.Client, guaranteeChangeAfter time.Duration, timerDuration time.Duration, timerTolerance time.Duration) *Process {
 	return &Process{
 		config:               config,
 		devicerepo:           devicerepo,
 		guaranteeChangeAfter: guaranteeChangeAfter,
+		platform:             platform,
 		metrics:              metrics,
+		timerDuration:        timerDuration,
+		timerTolerance:       timerTolerance,
 	}
 }
 
@@ -56,39 +85,17 @@ func (this *Process) getChangeGuaranteeDuration() time.Duration {
 // TODO: update process to new commands
 // TODO: add seneor command and check response
 func (this *Process) ProcessStartup(token string, info DeviceInfo) error {
-	this.receivedCommands.Store(0)
-	ids, err := this.ListCanaryProcessDeployments(token)
+	runId, runValue := this.run.reset()
+	this.deploymentId = ""
+	err := this.platform.DeleteDeploymentsByName(token, ExpectedCanaryDeploymentName)
 	if err != nil {
-		this.metrics.UncategorizedErr.Inc()
-		log.Println("ERROR: unexpected process deployment list count")
 		return err
 	}
-	//cleanup
-	for _, id := range ids {
-		err = this.DeleteProcess(token, id)
-		if err != nil {
-			this.metrics.UncategorizedErr.Inc()
-			log.Println("ERROR: DeleteProcess()", err)
-			return err
-		}
-	}
 
-	dt, err, _ := this.devicerepo.ReadDeviceType(info.DeviceTypeId, token)
+	serviceId, err := this.getCmdServiceId(token, info)
 	if err != nil {
-		this.metrics.UncategorizedErr.Inc()
-		log.Println("ERROR: ReadDeviceType()", err)
 		return err
 	}
-	serviceId := ""
-	for _, s := range dt.Services {
-		if s.LocalId == devicemetadata.CmdServiceLocalId {
-			serviceId = s.Id
-			break
-		}
-	}
-	if serviceId == "" {
-		return errors.New("ProcessStartup(): no cmd service id found")
-	}
 
 	//check prepared deployment
 	preparedDepl, err := this.PrepareProcessDeployment(token)
@@ -99,7 +106,7 @@ func (this *Process) ProcessStartup(token string, info DeviceInfo) error {
 		foundService := false
 		foundDevice := false
 		for _, e := range preparedDepl.Elements {
-			if e.BpmnId == "Task_0yuqb45" && e.Task != nil {
+			if e.BpmnId == CommandTaskBpmnId && e.Task != nil {
 				for _, o := range e.Task.Selection.SelectionOptions {
 					if o.Device != nil && o.Device.Id == info.Id {
 						foundDevice = true
@@ -123,16 +130,17 @@ func (this *Process) ProcessStartup(token string, info DeviceInfo) error {
 		}
 	}
 
-	deplId, err := this.DeployProcess(token, info.Id, serviceId)
+	deplId, err := this.DeployProcess(token, info.Id, serviceId, strconv.Itoa(runValue))
 	if err != nil {
 		this.metrics.ProcessDeploymentErr.Inc()
 		log.Println("ERROR: ProcessDeploymentErr", err)
 		return err
 	}
+	this.deploymentId = deplId
 
 	time.Sleep(this.getChangeGuaranteeDuration())
 
-	err = this.StartProcess(token, deplId)
+	err = this.platform.Start(token, deplId, map[string]string{RunIdParameter: runId})
 	if err != nil {
 		this.metrics.ProcessStartErr.Inc()
 		log.Println("ERROR: ProcessStartErr", err)
@@ -142,6 +150,29 @@ func (this *Process) ProcessStartup(token string, info DeviceInfo) error {
 	return nil
 }
 
+func (this *Process) getCmdServiceId(token string, info DeviceInfo) (serviceId string, err error) {
+	return this.getServiceId(token, info, devicemetadata.CmdServiceLocalId)
+}
+
+func (this *Process) getSensorServiceId(token string, info DeviceInfo) (serviceId string, err error) {
+	return this.getServiceId(token, info, devicemetadata.SensorServiceLocalId)
+}
+
+func (this *Process) getServiceId(token string, info DeviceInfo, localId string) (serviceId string, err error) {
+	dt, err, _ := this.devicerepo.ReadDeviceType(info.DeviceTypeId, token)
+	if err != nil {
+		this.metrics.UncategorizedErr.Inc()
+		log.Println("ERROR: ReadDeviceType()", err)
+		return "", err
+	}
+	for _, s := range dt.Services {
+		if s.LocalId == localId {
+			return s.Id, nil
+		}
+	}
+	return "", errors.New("no service id found for " + localId)
+}
+
 func (this *Process) ProcessTeardown(token string) error {
 	ids, err := this.ListCanaryProcessDeployments(token)
 	if err != nil {
@@ -153,66 +184,113 @@ func (this *Process) ProcessTeardown(token string) error {
 		log.Println("ERROR: unexpected process deployment list count")
 	}
 
-	unfilteredInstances, err := this.GetProcessInstances(token)
-	instances := []ProcessInstance{}
-	for _, e := range unfilteredInstances {
-		if e.ProcessDefinitionName == ExpectedCanaryDeploymentName {
-			instances = append(instances, e)
-		}
-	}
-
-	if err != nil {
-		this.metrics.UncategorizedErr.Inc()
-		log.Println("ERROR: unexpected process list count", err)
-	} else {
-		if len(instances) != 1 {
+	if this.deploymentId != "" {
+		instances, err := this.platform.WaitForFinishedInstances(token, this.deploymentId, 1)
+		switch {
+		case errors.Is(err, processplatform.ErrInstanceTimeout):
+			this.metrics.ProcessInstanceTimeoutErr.Inc()
+			log.Printf("ERROR: ProcessInstanceTimeoutErr %#v \n", instances)
+		case err != nil:
+			this.metrics.UncategorizedErr.Inc()
+			log.Println("ERROR: unable to get process instances", err)
+		case len(instances) != 1:
 			this.metrics.UncategorizedErr.Inc()
 			log.Println("ERROR: unexpected process instance list count", len(instances))
-		} else {
-			if instances[0].State != "COMPLETED" {
-				this.metrics.UnexpectedProcessInstanceStateErr.Inc()
-				log.Printf("ERROR: UnexpectedProcessInstanceStateErr %#v \n", instances)
-			} else {
-				this.metrics.ProcessInstanceDurationMs.Set(float64(instances[0].DurationInMillis))
-			}
+		case instances[0].State != "COMPLETED":
+			this.metrics.UnexpectedProcessInstanceStateErr.Inc()
+			log.Printf("ERROR: UnexpectedProcessInstanceStateErr %#v \n", instances)
+		default:
+			this.metrics.ProcessInstanceDurationMs.Set(float64(instances[0].DurationInMillis))
+			this.checkRunId(token, instances[0])
 		}
+		this.platform.DeleteFinishedInstanceHistory(token, instances)
 	}
 
-	//cleanup
-	for _, id := range ids {
-		err = this.DeleteProcess(token, id)
-		if err != nil {
-			this.metrics.UncategorizedErr.Inc()
-			log.Println("ERROR: DeleteProcess()", err)
-			return err
-		}
+	err = this.platform.DeleteDeploymentsByName(token, ExpectedCanaryDeploymentName)
+	if err != nil {
+		return err
 	}
 
-	if this.receivedCommands.Load() == 0 {
+	runId, runValue, received := this.run.get()
+	if received != 1 {
 		this.metrics.ProcessUnexpectedCommandCountError.Inc()
-		log.Println("ERROR: ProcessUnexpectedCommandCountError", this.receivedCommands.Load())
+		log.Println("ERROR: ProcessUnexpectedCommandCountError", received, "commands with value", runValue, "received in run", runId, "; expected 1")
 	}
 	return nil
 }
 
+// checkRunId ensures that the instance has been started by the current run
+func (this *Process) checkRunId(token string, instance ProcessInstance) {
+	runId, _, _ := this.run.get()
+	variables, err := this.platform.GetProcessInstanceHistoryVariables(token, instance.Id)
+	if err != nil {
+		this.metrics.UncategorizedErr.Inc()
+		log.Println("ERROR: GetProcessInstanceHistoryVariables()", err)
+		return
+	}
+	for _, variable := range variables {
+		if variable.Name == RunIdParameter && variable.Value == runId {
+			return
+		}
+	}
+	this.metrics.ProcessUnexpectedRunIdErr.Inc()
+	log.Printf("ERROR: ProcessUnexpectedRunIdErr expected %v = %v in %#v\n", RunIdParameter, runId, variables)
+}
+
 func (this *Process) NotifyCommand(topic string, payload []byte) error {
-	this.receivedCommands.Add(1)
 	message := RequestEnvelope{}
 	err := json.Unmarshal(payload, &message)
 	if err != nil {
 		log.Println("ERROR: unable to json unmarshal", string(payload), err)
 		return err
 	}
-	expectedMessagePayload := map[string]string{
-		"data":     `<commands><valueCommand value="42"/></commands>`,
-		"metadata": "on",
+	duplicate := this.run.isDuplicate(message.CorrelationId)
+	if duplicate {
+		this.metrics.ProcessDuplicateCommandErr.Inc()
+		log.Println("ERROR: ProcessDuplicateCommandErr", message.CorrelationId, message.Payload)
+	}
+	if reflect.DeepEqual(message.Payload, getExpectedCommandPayload(IncidentCommandValue)) {
+		if !duplicate {
+			this.receivedIncidentCommands.Add(1)
+		}
+		return ErrIncidentCommand //redelivered incident commands have to fail as well
+	}
+	if duplicate {
+		return nil
+	}
+	if reflect.DeepEqual(message.Payload, getExpectedCommandPayload(GroupCommandValue)) {
+		this.receivedGroupCommands.Add(1)
+		return nil
+	}
+	if reflect.DeepEqual(message.Payload, getExpectedCommandPayload(TimerCommandValue)) {
+		this.timerCommandReceivedAt.CompareAndSwap(0, time.Now().UnixNano())
+		return nil
 	}
-	if !reflect.DeepEqual(message.Payload, expectedMessagePayload) {
-		return errors.New("unexpected command message:" + fmt.Sprintf("%#v", message.Payload))
+	if this.run.notify(message.Payload) {
+		return nil
 	}
+	if this.multiTask.notify(message.Payload) {
+		return nil
+	}
+	if this.startParameter.notify(message.Payload) {
+		return nil
+	}
+	if this.conversions.notify(message.Payload) {
+		return nil
+	}
+	//stray commands, e.g. redelivered from earlier runs, are answered to let their tasks finish
+	this.metrics.ProcessStrayCommandErr.Inc()
+	log.Printf("ERROR: ProcessStrayCommandErr unexpected command message: %#v\n", message.Payload)
 	return nil
 }
 
+func getExpectedCommandPayload(value interface{}) map[string]string {
+	return map[string]string{
+		"data":     fmt.Sprintf(`<commands><valueCommand value="%v"/></commands>`, value),
+		"metadata": "on",
+	}
+}
+
 type RequestEnvelope struct {
 	CorrelationId      string            `json:"correlation_id"`
 	Payload            map[string]string `json:"payload"`
@@ -0,0 +1,83 @@
+/*
+ * Copyright (c) 2023 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package process
+
+import (
+	"github.com/google/uuid"
+	"math/rand"
+	"reflect"
+	"sync"
+)
+
+// RunIdParameter is the start parameter, which tags the process instance with the id of the canary run
+const RunIdParameter = "canary_run_id"
+
+// run command values are drawn from this range, to never collide with the fixed values of the other process checks
+const minRunCommandValue = 1000
+const maxRunCommandValue = 100000
+
+// commandRun identifies the command of the current canary process run by its value
+// and detects redelivered commands by their correlation id
+type commandRun struct {
+	mux            sync.Mutex
+	id             string
+	value          int
+	received       int
+	correlationIds map[string]bool
+}
+
+// reset starts a new run with a fresh run id and command value
+func (this *commandRun) reset() (id string, value int) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.id = uuid.NewString()
+	this.value = minRunCommandValue + rand.Intn(maxRunCommandValue-minRunCommandValue)
+	this.received = 0
+	this.correlationIds = map[string]bool{}
+	return this.id, this.value
+}
+
+// isDuplicate remembers the correlation id and returns true if it has already been seen in this run
+func (this *commandRun) isDuplicate(correlationId string) bool {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	if this.correlationIds == nil {
+		this.correlationIds = map[string]bool{}
+	}
+	if this.correlationIds[correlationId] {
+		return true
+	}
+	this.correlationIds[correlationId] = true
+	return false
+}
+
+// notify returns true and counts the command if the payload carries the value of the current run
+func (this *commandRun) notify(payload map[string]string) bool {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	if this.id == "" || !reflect.DeepEqual(payload, getExpectedCommandPayload(this.value)) {
+		return false
+	}
+	this.received = this.received + 1
+	return true
+}
+
+func (this *commandRun) get() (id string, value int, received int) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	return this.id, this.value, this.received
+}
@@ -0,0 +1,217 @@
+/*
+ * Copyright (c) 2023 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package process
+
+import (
+	"errors"
+	"github.com/SENERGY-Platform/snowflake-canary/pkg/bpmn"
+	"github.com/SENERGY-Platform/snowflake-canary/pkg/processplatform"
+	"log"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const ExpectedCanaryStartParameterDeploymentName = "snowflake_canary_start_parameter_process"
+
+// StartParameterId is the start parameter, which is used as input of the command task
+const StartParameterId = "canary_input"
+
+// StartParameterCommandValue is set as start parameter of the first run
+const StartParameterCommandValue = 49
+
+// StartParameterDefaultValue is the default of the initial deployment; it is never expected as command
+const StartParameterDefaultValue = 50
+
+// StartParameterUpdatedDefaultValue is the default of the updated deployment, used by the second run
+const StartParameterUpdatedDefaultValue = 51
+
+type startParameterState struct {
+	mux      sync.Mutex
+	active   bool
+	received []int
+}
+
+func (this *startParameterState) reset(active bool) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.active = active
+	this.received = nil
+}
+
+// notify returns true and remembers the value if the payload is a command of the start parameter process
+func (this *startParameterState) notify(payload map[string]string) bool {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	if !this.active {
+		return false
+	}
+	for _, value := range []int{StartParameterCommandValue, StartParameterDefaultValue, StartParameterUpdatedDefaultValue} {
+		if reflect.DeepEqual(payload, getExpectedCommandPayload(value)) {
+			this.received = append(this.received, value)
+			return true
+		}
+	}
+	return false
+}
+
+func (this *startParameterState) get() []int {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	return append([]int{}, this.received...)
+}
+
+func (this *Process) getStartParameterVariant(info DeviceInfo, serviceId string, defaultValue int) DeploymentVariant {
+	return DeploymentVariant{
+		Name:      ExpectedCanaryStartParameterDeploymentName,
+		ProcessId: "snowflake_canary_start_parameter_command",
+		DeviceId:  info.Id,
+		ServiceId: serviceId,
+		Input:     "${" + StartParameterId + "}",
+		StartParameters: []bpmn.StartParameterInfo{{
+			Id:      StartParameterId,
+			Label:   "Target Temperature",
+			Default: strconv.Itoa(defaultValue),
+		}},
+	}
+}
+
+// StartParameterProcessStartup deploys a process with a start parameter and starts it with StartParameterCommandValue
+func (this *Process) StartParameterProcessStartup(token string, info DeviceInfo) error {
+	this.metrics.StartParameterProcessCheckCount.Inc()
+	this.startParameterDeploymentId = ""
+	this.startParameter.reset(true)
+	err := this.platform.DeleteDeploymentsByName(token, ExpectedCanaryStartParameterDeploymentName)
+	if err != nil {
+		return err
+	}
+	serviceId, err := this.getCmdServiceId(token, info)
+	if err != nil {
+		return err
+	}
+	this.startParameterServiceId = serviceId
+	this.startParameterDeviceInfo = info
+	deplId, err := this.DeployProcessVariant(token, this.getStartParameterVariant(info, serviceId, StartParameterDefaultValue))
+	if err != nil {
+		this.metrics.ProcessDeploymentErr.Inc()
+		log.Println("ERROR: ProcessDeploymentErr start parameter", err)
+		return err
+	}
+	this.startParameterDeploymentId = deplId
+
+	time.Sleep(this.getChangeGuaranteeDuration())
+
+	err = this.platform.Start(token, deplId, map[string]string{StartParameterId: strconv.Itoa(StartParameterCommandValue)})
+	if err != nil {
+		this.metrics.ProcessStartErr.Inc()
+		log.Println("ERROR: ProcessStartErr", err)
+		return err
+	}
+	return nil
+}
+
+// StartParameterProcessTeardown checks the parameterized run, updates the deployment with a changed parameter default,
+// runs the updated deployment without parameters and checks that the new default reaches the command
+func (this *Process) StartParameterProcessTeardown(token string) error {
+	if this.startParameterDeploymentId != "" {
+		this.checkStartParameterProcess(token)
+	}
+	this.startParameter.reset(false)
+	return this.platform.DeleteDeploymentsByName(token, ExpectedCanaryStartParameterDeploymentName)
+}
+
+func (this *Process) checkStartParameterProcess(token string) {
+	if !this.checkStartParameterRun(token, []int{StartParameterCommandValue}) {
+		return
+	}
+	before, err := this.platform.GetProcessDefinition(token, this.startParameterDeploymentId)
+	if err != nil {
+		this.metrics.UncategorizedErr.Inc()
+		log.Println("ERROR: GetProcessDefinition()", err)
+		return
+	}
+
+	err = this.UpdateProcessVariant(token, this.startParameterDeploymentId, this.getStartParameterVariant(this.startParameterDeviceInfo, this.startParameterServiceId, StartParameterUpdatedDefaultValue))
+	if err != nil {
+		this.metrics.ProcessDeploymentUpdateErr.Inc()
+		log.Println("ERROR: ProcessDeploymentUpdateErr", err)
+		return
+	}
+
+	time.Sleep(this.getChangeGuaranteeDuration())
+
+	ids, err := this.platform.ListDeploymentIdsByName(token, ExpectedCanaryStartParameterDeploymentName)
+	if err != nil {
+		this.metrics.UncategorizedErr.Inc()
+		log.Println("ERROR: unable to list start parameter process deployments", err)
+		return
+	}
+	if len(ids) != 1 || ids[0] != this.startParameterDeploymentId {
+		this.metrics.UnexpectedProcessDeploymentUpdateErr.Inc()
+		log.Println("ERROR: UnexpectedProcessDeploymentUpdateErr deployment id changed by update", this.startParameterDeploymentId, ids)
+		return
+	}
+	after, err := this.platform.GetProcessDefinition(token, this.startParameterDeploymentId)
+	if err != nil {
+		this.metrics.UncategorizedErr.Inc()
+		log.Println("ERROR: GetProcessDefinition()", err)
+		return
+	}
+	if after.Id == before.Id {
+		this.metrics.UnexpectedProcessDeploymentUpdateErr.Inc()
+		log.Printf("ERROR: UnexpectedProcessDeploymentUpdateErr process definition not replaced by update %#v\n", after)
+	}
+
+	err = this.platform.Start(token, this.startParameterDeploymentId, nil)
+	if err != nil {
+		this.metrics.ProcessStartErr.Inc()
+		log.Println("ERROR: ProcessStartErr", err)
+		return
+	}
+	this.checkStartParameterRun(token, []int{StartParameterCommandValue, StartParameterUpdatedDefaultValue})
+}
+
+// checkStartParameterRun waits for all instances of the deployment and compares the received commands with expected
+func (this *Process) checkStartParameterRun(token string, expected []int) (ok bool) {
+	instances, err := this.platform.WaitForFinishedInstances(token, this.startParameterDeploymentId, 1)
+	switch {
+	case errors.Is(err, processplatform.ErrInstanceTimeout):
+		this.metrics.ProcessInstanceTimeoutErr.Inc()
+		log.Printf("ERROR: ProcessInstanceTimeoutErr start parameter %#v \n", instances)
+		return false
+	case err != nil:
+		this.metrics.UncategorizedErr.Inc()
+		log.Println("ERROR: unable to get start parameter process instances", err)
+		return false
+	}
+	this.platform.DeleteFinishedInstanceHistory(token, instances)
+	for _, instance := range instances {
+		if instance.State != "COMPLETED" {
+			this.metrics.UnexpectedProcessInstanceStateErr.Inc()
+			log.Printf("ERROR: UnexpectedProcessInstanceStateErr %#v \n", instances)
+			return false
+		}
+	}
+	received := this.startParameter.get()
+	if !reflect.DeepEqual(received, expected) {
+		this.metrics.UnexpectedStartParameterCommandErr.Inc()
+		log.Println("ERROR: UnexpectedStartParameterCommandErr received", received, "expected", expected)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,119 @@
+/*
+ * Copyright (c) 2023 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package process
+
+import (
+	"errors"
+	"github.com/SENERGY-Platform/snowflake-canary/pkg/processplatform"
+	"log"
+	"strconv"
+	"time"
+)
+
+const ExpectedCanaryTimerDeploymentName = "snowflake_canary_timer_process"
+
+// TimerCommandValue marks the command of the timer process
+const TimerCommandValue = 48
+
+// TimerProcessStartup deploys and starts a process, which waits for timer_process_duration before sending its command
+func (this *Process) TimerProcessStartup(token string, info DeviceInfo) error {
+	this.metrics.TimerProcessCheckCount.Inc()
+	this.timerDeploymentId = ""
+	this.timerCommandReceivedAt.Store(0)
+	err := this.platform.DeleteDeploymentsByName(token, ExpectedCanaryTimerDeploymentName)
+	if err != nil {
+		return err
+	}
+	serviceId, err := this.getCmdServiceId(token, info)
+	if err != nil {
+		return err
+	}
+	deplId, err := this.DeployProcessVariant(token, DeploymentVariant{
+		Name:          ExpectedCanaryTimerDeploymentName,
+		ProcessId:     "snowflake_canary_timer_command",
+		DeviceId:      info.Id,
+		ServiceId:     serviceId,
+		Input:         strconv.Itoa(TimerCommandValue),
+		TimerDuration: isoDuration(this.timerDuration),
+	})
+	if err != nil {
+		this.metrics.ProcessDeploymentErr.Inc()
+		log.Println("ERROR: ProcessDeploymentErr timer", err)
+		return err
+	}
+	this.timerDeploymentId = deplId
+
+	time.Sleep(this.getChangeGuaranteeDuration())
+
+	this.timerProcessStartedAt = time.Now()
+	err = this.platform.Start(token, deplId, nil)
+	if err != nil {
+		this.metrics.ProcessStartErr.Inc()
+		log.Println("ERROR: ProcessStartErr", err)
+		return err
+	}
+	return nil
+}
+
+// TimerProcessTeardown waits for the timer command, exports the timer accuracy and checks that the instance completes.
+// the command is expected between timer_process_duration and timer_process_duration + timer_process_tolerance after the process start.
+func (this *Process) TimerProcessTeardown(token string) error {
+	if this.timerDeploymentId != "" {
+		this.checkTimerProcess(token)
+	}
+	return this.platform.DeleteDeploymentsByName(token, ExpectedCanaryTimerDeploymentName)
+}
+
+func (this *Process) checkTimerProcess(token string) {
+	deadline := this.timerProcessStartedAt.Add(this.timerDuration + this.timerTolerance)
+	for this.timerCommandReceivedAt.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(this.platform.InstancePollInterval())
+	}
+	receivedAt := this.timerCommandReceivedAt.Load()
+	if receivedAt == 0 {
+		this.metrics.UnexpectedTimerProcessCommandErr.Inc()
+		log.Println("ERROR: UnexpectedTimerProcessCommandErr no command received within", this.timerDuration+this.timerTolerance)
+	} else {
+		delay := time.Unix(0, receivedAt).Sub(this.timerProcessStartedAt)
+		this.metrics.TimerProcessAccuracyMs.Set(float64((delay - this.timerDuration).Milliseconds()))
+		if delay < this.timerDuration || delay > this.timerDuration+this.timerTolerance {
+			this.metrics.UnexpectedTimerProcessCommandErr.Inc()
+			log.Printf("ERROR: UnexpectedTimerProcessCommandErr command received %v after process start; expected between %v and %v\n", delay, this.timerDuration, this.timerDuration+this.timerTolerance)
+		}
+	}
+
+	instances, err := this.platform.WaitForFinishedInstances(token, this.timerDeploymentId, 1)
+	switch {
+	case errors.Is(err, processplatform.ErrInstanceTimeout):
+		this.metrics.ProcessInstanceTimeoutErr.Inc()
+		log.Printf("ERROR: ProcessInstanceTimeoutErr timer %#v \n", instances)
+	case err != nil:
+		this.metrics.UncategorizedErr.Inc()
+		log.Println("ERROR: unable to get timer process instances", err)
+	case len(instances) != 1:
+		this.metrics.UncategorizedErr.Inc()
+		log.Println("ERROR: unexpected timer process instance list count", len(instances))
+	case instances[0].State != "COMPLETED":
+		this.metrics.UnexpectedProcessInstanceStateErr.Inc()
+		log.Printf("ERROR: UnexpectedProcessInstanceStateErr %#v \n", instances)
+	}
+	this.platform.DeleteFinishedInstanceHistory(token, instances)
+}
+
+func isoDuration(duration time.Duration) string {
+	return "PT" + strconv.FormatFloat(duration.Seconds(), 'f', -1, 64) + "S"
+}
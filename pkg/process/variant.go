@@ -0,0 +1,146 @@
+/*
+ * Copyright (c) 2023 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package process
+
+import (
+	"errors"
+	"github.com/SENERGY-Platform/snowflake-canary/pkg/bpmn"
+	"log"
+)
+
+const CanaryProcessId = "snowflake_canary_command"
+const CommandTaskBpmnId = "Task_0yuqb45"
+const CommandTaskName = "Thermostat Set Target Temperature"
+
+// DeploymentVariant describes a deployment of the command process.
+// either DeviceId and ServiceId or DeviceGroupId select the device; an empty CharacteristicId uses the configured canary_process_characteristic_id.
+type DeploymentVariant struct {
+	Name             string
+	ProcessId        string //bpmn process id; must differ between variants deployed at the same time
+	DeviceId         string
+	ServiceId        string
+	DeviceGroupId    string
+	CharacteristicId string
+	Input            string
+	NoRetries        bool   //the first failed command creates an incident
+	TimerDuration    string //iso 8601 duration of a timer event before the command task; empty for no timer
+	StartParameters  []bpmn.StartParameterInfo
+}
+
+// getProcessBuilder creates the command process: start -> (timer ->) set target temperature -> end
+func (this *Process) getProcessBuilder(variant DeploymentVariant) (*bpmn.Builder, error) {
+	characteristicId := variant.CharacteristicId
+	if characteristicId == "" {
+		characteristicId = this.config.CanaryProcessCharacteristicId
+	}
+	retries := 3
+	if variant.NoRetries {
+		retries = 0
+	}
+	task, err := this.resolveTaskInfo(bpmn.TaskInfo{
+		FunctionId:       this.config.CanaryCmdFunctionId,
+		DeviceClassId:    this.config.CanaryDeviceClassId,
+		CharacteristicId: characteristicId,
+		Retries:          retries,
+	})
+	if err != nil {
+		return nil, err
+	}
+	builder := bpmn.NewBuilder(variant.ProcessId)
+	nodes := []string{builder.StartEvent("StartEvent_1", variant.StartParameters...)}
+	if variant.TimerDuration != "" {
+		nodes = append(nodes, builder.TimerEvent("Timer_1", "Wait "+variant.TimerDuration, variant.TimerDuration))
+	}
+	nodes = append(nodes,
+		builder.ServiceTask(CommandTaskBpmnId, CommandTaskName, task),
+		builder.EndEvent("EndEvent_1"),
+	)
+	builder.Chain(nodes...)
+	return builder, nil
+}
+
+// resolveTaskInfo reads the function, device class and aspect of the task,
+// so that the task payload contains the same fields as a deployment prepared by the process designer
+func (this *Process) resolveTaskInfo(task bpmn.TaskInfo) (bpmn.TaskInfo, error) {
+	if task.FunctionId != "" {
+		function, err, _ := this.devicerepo.GetFunction(task.FunctionId)
+		if err != nil {
+			this.metrics.UncategorizedErr.Inc()
+			log.Println("ERROR: GetFunction()", task.FunctionId, err)
+			return task, err
+		}
+		task.Function = &function
+	}
+	if task.DeviceClassId != "" {
+		deviceClass, err, _ := this.devicerepo.GetDeviceClass(task.DeviceClassId)
+		if err != nil {
+			this.metrics.UncategorizedErr.Inc()
+			log.Println("ERROR: GetDeviceClass()", task.DeviceClassId, err)
+			return task, err
+		}
+		task.DeviceClass = &deviceClass
+	}
+	if task.AspectId != "" {
+		aspect, err, _ := this.devicerepo.GetAspect(task.AspectId)
+		if err != nil {
+			this.metrics.UncategorizedErr.Inc()
+			log.Println("ERROR: GetAspect()", task.AspectId, err)
+			return task, err
+		}
+		task.Aspect = &aspect
+	}
+	return task, nil
+}
+
+func (this *Process) getDeploymentVariantMessage(variant DeploymentVariant) (deployment bpmn.Deployment, err error) {
+	builder, err := this.getProcessBuilder(variant)
+	if err != nil {
+		return deployment, err
+	}
+	deployment, err = builder.Deployment(variant.Name)
+	if err != nil {
+		return deployment, err
+	}
+	task := deployment.Element(CommandTaskBpmnId)
+	if task == nil || task.Task == nil {
+		return deployment, errors.New("unexpected deployment: missing command task")
+	}
+	task.Task.Parameter["inputs"] = variant.Input
+	if variant.DeviceGroupId != "" {
+		task.Task.Selection.SelectDeviceGroup(variant.DeviceGroupId)
+	} else {
+		task.Task.Selection.SelectDevice(variant.DeviceId, variant.ServiceId)
+	}
+	return deployment, nil
+}
+
+func (this *Process) DeployProcessVariant(token string, variant DeploymentVariant) (deploymentId string, err error) {
+	deployment, err := this.getDeploymentVariantMessage(variant)
+	if err != nil {
+		return "", err
+	}
+	return this.platform.Deploy(token, deployment)
+}
+
+// UpdateProcessVariant replaces the deployment with the variant, keeping the deployment id
+func (this *Process) UpdateProcessVariant(token string, deploymentId string, variant DeploymentVariant) error {
+	deployment, err := this.getDeploymentVariantMessage(variant)
+	if err != nil {
+		return err
+	}
+	return this.platform.Update(token, deploymentId, deployment)
+}
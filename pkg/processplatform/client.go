@@ -0,0 +1,102 @@
+/*
+ * Copyright (c) 2023 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package processplatform
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/SENERGY-Platform/snowflake-canary/pkg/configuration"
+	"github.com/SENERGY-Platform/snowflake-canary/pkg/metrics"
+	"io"
+	"net/http"
+	"time"
+)
+
+const requestTimeout = time.Minute
+
+// Client wraps the process-deployment, process-engine-wrapper, process-incident and notification apis.
+// every request is exported as snowflake_canary_process_platform_request_* metric, labeled by operation.
+type Client struct {
+	config               configuration.Config
+	metrics              *metrics.Metrics
+	http                 *http.Client
+	instanceTimeout      time.Duration
+	instancePollInterval time.Duration
+}
+
+func New(config configuration.Config, metrics *metrics.Metrics, instanceTimeout time.Duration, instancePollInterval time.Duration) *Client {
+	return &Client{
+		config:               config,
+		metrics:              metrics,
+		http:                 &http.Client{Timeout: requestTimeout},
+		instanceTimeout:      instanceTimeout,
+		instancePollInterval: instancePollInterval,
+	}
+}
+
+// do sends body as json (if not nil) and decodes the response into result (if not nil)
+func (this *Client) do(operation string, token string, method string, endpoint string, body interface{}, result interface{}) (err error) {
+	this.metrics.ProcessPlatformRequestCount.WithLabelValues(operation).Inc()
+	start := time.Now()
+	err = this.request(token, method, endpoint, body, result)
+	this.metrics.ProcessPlatformRequestLatencyMs.WithLabelValues(operation).Set(float64(time.Since(start).Milliseconds()))
+	if err != nil {
+		this.metrics.ProcessPlatformRequestErr.WithLabelValues(operation).Inc()
+		return fmt.Errorf("%v: %w", operation, err)
+	}
+	return nil
+}
+
+func (this *Client) request(token string, method string, endpoint string, body interface{}, result interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		msg, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewBuffer(msg)
+	}
+	req, err := http.NewRequest(method, endpoint, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := this.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode > 299 {
+		temp, _ := io.ReadAll(resp.Body) //read error response end ensure that resp.Body is read to EOF
+		return fmt.Errorf("unexpected response %v: %v", resp.StatusCode, string(temp))
+	}
+	if result == nil {
+		_, _ = io.ReadAll(resp.Body) //ensure resp.Body is read to EOF
+		return nil
+	}
+	err = json.NewDecoder(resp.Body).Decode(result)
+	if err != nil {
+		_, _ = io.ReadAll(resp.Body) //ensure resp.Body is read to EOF
+		return err
+	}
+	return nil
+}
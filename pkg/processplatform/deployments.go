@@ -0,0 +1,113 @@
+/*
+ * Copyright (c) 2023 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package processplatform
+
+import (
+	"github.com/SENERGY-Platform/snowflake-canary/pkg/bpmn"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+const deploymentPageSize = 200
+
+// Deploy creates the deployment and returns its id
+func (this *Client) Deploy(token string, deployment bpmn.Deployment) (deploymentId string, err error) {
+	wrapper := Wrapper{}
+	err = this.do("deploy", token, http.MethodPost, this.config.ProcessDeploymentUrl+"/v3/deployments?source=sepl", deployment, &wrapper)
+	return wrapper.Id, err
+}
+
+// Update replaces the deployment in place; the deployment id stays the same
+func (this *Client) Update(token string, deploymentId string, deployment bpmn.Deployment) (err error) {
+	deployment.Id = deploymentId
+	return this.do("update_deployment", token, http.MethodPut, this.config.ProcessDeploymentUrl+"/v3/deployments/"+url.PathEscape(deploymentId)+"?source=sepl", deployment, nil)
+}
+
+func (this *Client) DeleteDeployment(token string, deploymentId string) (err error) {
+	return this.do("delete_deployment", token, http.MethodDelete, this.config.ProcessDeploymentUrl+"/v3/deployments/"+url.PathEscape(deploymentId), nil, nil)
+}
+
+// ListDeployments pages through all deployments of the user
+func (this *Client) ListDeployments(token string) (result []Wrapper, err error) {
+	offset := 0
+	for {
+		sub, err := this.listDeployments(token, deploymentPageSize, offset)
+		if err != nil {
+			return result, err
+		}
+		result = append(result, sub...)
+		if len(sub) < deploymentPageSize {
+			return result, nil
+		}
+		offset = deploymentPageSize + offset
+		log.Println("ListDeployments offset =", offset)
+	}
+}
+
+func (this *Client) ListDeploymentIdsByName(token string, name string) (ids []string, err error) {
+	deployments, err := this.ListDeployments(token)
+	if err != nil {
+		return ids, err
+	}
+	for _, w := range deployments {
+		if w.Name == name {
+			ids = append(ids, w.Id)
+		}
+	}
+	return ids, nil
+}
+
+func (this *Client) listDeployments(token string, limit int, offset int) (wrappers []Wrapper, err error) {
+	query := url.Values{"maxResults": {strconv.Itoa(limit)}}
+	if offset > 0 {
+		query.Set("firstResult", strconv.Itoa(offset))
+	}
+	err = this.do("list_deployments", token, http.MethodGet, this.config.ProcessEngineWrapperUrl+"/v2/deployments?"+query.Encode(), nil, &wrappers)
+	return wrappers, err
+}
+
+// DeleteDeploymentsByName removes all deployments with the given name; errors are logged and counted
+func (this *Client) DeleteDeploymentsByName(token string, name string) (err error) {
+	defer func() {
+		if err != nil {
+			this.metrics.UncategorizedErr.Inc()
+			log.Println("ERROR: unable to delete process deployments", name, err)
+		}
+	}()
+	ids, err := this.ListDeploymentIdsByName(token, name)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		err = this.DeleteDeployment(token, id)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PrepareDeployment lets the process-deployment service list the selectable devices and services of every element
+func (this *Client) PrepareDeployment(token string, xml string, svg string) (result PreparedDeployment, err error) {
+	err = this.do("prepare_deployment", token, http.MethodPost, this.config.ProcessDeploymentUrl+"/v3/prepared-deployments", map[string]interface{}{
+		"xml": xml,
+		"svg": svg,
+	}, &result)
+	return result, err
+}
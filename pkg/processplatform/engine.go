@@ -0,0 +1,131 @@
+/*
+ * Copyright (c) 2023 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package processplatform
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+var ErrInstanceTimeout = errors.New("process instances not finished within process_instance_timeout")
+
+// Start starts the deployment; the parameters are set as process variables
+func (this *Client) Start(token string, deploymentId string, parameters map[string]string) (err error) {
+	endpoint := this.config.ProcessEngineWrapperUrl + "/v2/deployments/" + url.PathEscape(deploymentId) + "/start"
+	if len(parameters) > 0 {
+		query := url.Values{}
+		for key, value := range parameters {
+			query.Set(key, value)
+		}
+		endpoint = endpoint + "?" + query.Encode()
+	}
+	return this.do("start", token, http.MethodGet, endpoint, nil, nil)
+}
+
+func (this *Client) GetProcessDefinition(token string, deploymentId string) (result ProcessDefinition, err error) {
+	err = this.do("get_definition", token, http.MethodGet, this.config.ProcessEngineWrapperUrl+"/v2/deployments/"+url.PathEscape(deploymentId)+"/definition", nil, &result)
+	return result, err
+}
+
+func (this *Client) GetProcessInstancesByDefinitionId(token string, definitionId string) (result []ProcessInstance, err error) {
+	unfiltered := []ProcessInstance{}
+	err = this.do("list_instances", token, http.MethodGet, this.config.ProcessEngineWrapperUrl+"/v2/history/filtered/process-instances?maxResults=100&processDefinitionId="+url.QueryEscape(definitionId), nil, &unfiltered)
+	if err != nil {
+		return result, err
+	}
+	//guard against ignored filter parameters
+	for _, instance := range unfiltered {
+		if instance.ProcessDefinitionId == definitionId {
+			result = append(result, instance)
+		}
+	}
+	return result, nil
+}
+
+func (this *Client) GetProcessInstanceHistoryVariables(token string, instanceId string) (result []HistoricVariable, err error) {
+	err = this.do("get_instance_variables", token, http.MethodGet, this.config.ProcessEngineWrapperUrl+"/v2/history/process-instances/"+url.PathEscape(instanceId)+"/variables", nil, &result)
+	return result, err
+}
+
+func (this *Client) DeleteProcessInstanceHistory(token string, instanceId string) (err error) {
+	return this.do("delete_instance_history", token, http.MethodDelete, this.config.ProcessEngineWrapperUrl+"/v2/history/process-instances/"+url.PathEscape(instanceId), nil, nil)
+}
+
+// WaitForFinishedInstances polls the instance history of the deployments process definition,
+// until at least expectedCount instances exist and none of them is running.
+// on timeout the instances found so far are returned with ErrInstanceTimeout.
+func (this *Client) WaitForFinishedInstances(token string, deploymentId string, expectedCount int) (instances []ProcessInstance, err error) {
+	definition, err := this.GetProcessDefinition(token, deploymentId)
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(this.instanceTimeout)
+	for {
+		instances, err = this.GetProcessInstancesByDefinitionId(token, definition.Id)
+		if err != nil {
+			return instances, err
+		}
+		if len(instances) >= expectedCount && allInstancesFinished(instances) {
+			return instances, nil
+		}
+		if time.Now().After(deadline) {
+			return instances, ErrInstanceTimeout
+		}
+		time.Sleep(this.instancePollInterval)
+	}
+}
+
+// DeleteFinishedInstanceHistory removes finished instances from the history, to keep instance counts of later runs meaningful.
+// errors are logged and counted, because a failed cleanup must not fail the check.
+func (this *Client) DeleteFinishedInstanceHistory(token string, instances []ProcessInstance) {
+	for _, instance := range instances {
+		if !IsInstanceFinished(instance) {
+			continue
+		}
+		err := this.DeleteProcessInstanceHistory(token, instance.Id)
+		if err != nil {
+			this.metrics.UncategorizedErr.Inc()
+			log.Println("ERROR: DeleteFinishedInstanceHistory()", err)
+		}
+	}
+}
+
+func allInstancesFinished(instances []ProcessInstance) bool {
+	for _, instance := range instances {
+		if !IsInstanceFinished(instance) {
+			return false
+		}
+	}
+	return true
+}
+
+func IsInstanceFinished(instance ProcessInstance) bool {
+	return instance.State != "ACTIVE" && instance.State != "SUSPENDED"
+}
+
+// InstancePollInterval is the interval used to poll the process platform for state changes
+func (this *Client) InstancePollInterval() time.Duration {
+	return this.instancePollInterval
+}
+
+// InstanceTimeout is the maximal time to wait for state changes of the process platform
+func (this *Client) InstanceTimeout() time.Duration {
+	return this.instanceTimeout
+}
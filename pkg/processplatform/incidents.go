@@ -0,0 +1,31 @@
+/*
+ * Copyright (c) 2023 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package processplatform
+
+import (
+	"net/http"
+	"net/url"
+)
+
+func (this *Client) GetIncidentsByDefinitionId(token string, definitionId string) (result []Incident, err error) {
+	err = this.do("list_incidents", token, http.MethodGet, this.config.ProcessIncidentApiUrl+"/incidents?limit=100&process_definition_id="+url.QueryEscape(definitionId), nil, &result)
+	return result, err
+}
+
+func (this *Client) DeleteIncident(token string, incidentId string) (err error) {
+	return this.do("delete_incident", token, http.MethodDelete, this.config.ProcessIncidentApiUrl+"/incidents/"+url.PathEscape(incidentId), nil, nil)
+}
@@ -0,0 +1,164 @@
+/*
+ * Copyright (c) 2023 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package processplatform
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+type Wrapper struct {
+	Id             string `json:"id"`
+	Name           string `json:"name"`
+	DeploymentTime string `json:"deploymentTime"`
+}
+
+// camunda formats dates like 2023-04-19T08:48:13.123+0000
+const camundaTimeLayout = "2006-01-02T15:04:05.000-0700"
+
+// DeployedAt parses DeploymentTime, which may be formatted by camunda or as RFC 3339
+func (this Wrapper) DeployedAt() (time.Time, error) {
+	result, err := time.Parse(camundaTimeLayout, this.DeploymentTime)
+	if err != nil {
+		return time.Parse(time.RFC3339Nano, this.DeploymentTime)
+	}
+	return result, nil
+}
+
+type ProcessDefinition struct {
+	Id           string `json:"id"`
+	Key          string `json:"key"`
+	Name         string `json:"name"`
+	Version      int    `json:"version"`
+	DeploymentId string `json:"deploymentId"`
+}
+
+type ProcessInstance struct {
+	Id                    string `json:"id"`
+	ProcessDefinitionId   string `json:"processDefinitionId"`
+	ProcessDefinitionName string `json:"processDefinitionName"`
+	StartTime             string `json:"startTime"`
+	EndTime               string `json:"endTime"`
+	DurationInMillis      int    `json:"durationInMillis"`
+	State                 string `json:"state"`
+}
+
+// StartedAt parses StartTime, which may be formatted by camunda or as RFC 3339
+func (this ProcessInstance) StartedAt() (time.Time, error) {
+	result, err := time.Parse(camundaTimeLayout, this.StartTime)
+	if err != nil {
+		return time.Parse(time.RFC3339Nano, this.StartTime)
+	}
+	return result, nil
+}
+
+type HistoricVariable struct {
+	Name  string      `json:"name"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+// Float accepts numbers and numeric strings, because camunda may store task outputs as json strings
+func (this HistoricVariable) Float() (float64, error) {
+	switch v := this.Value.(type) {
+	case float64:
+		return v, nil
+	case string:
+		return strconv.ParseFloat(v, 64)
+	default:
+		return 0, fmt.Errorf("unexpected value type %T of variable %v", this.Value, this.Name)
+	}
+}
+
+type Incident struct {
+	Id                  string    `json:"id"`
+	ExternalTaskId      string    `json:"external_task_id"`
+	ProcessInstanceId   string    `json:"process_instance_id"`
+	ProcessDefinitionId string    `json:"process_definition_id"`
+	WorkerId            string    `json:"worker_id"`
+	ErrorMessage        string    `json:"error_message"`
+	Time                time.Time `json:"time"`
+	DeploymentName      string    `json:"deployment_name"`
+}
+
+type PreparedDeployment struct {
+	Id       string    `json:"id"`
+	Name     string    `json:"name"`
+	Elements []Element `json:"elements"`
+}
+
+type Element struct {
+	BpmnId           string            `json:"bpmn_id"`
+	Task             *Task             `json:"task"`
+	ConditionalEvent *ConditionalEvent `json:"conditional_event"`
+}
+
+type Task struct {
+	Selection Selection `json:"selection"`
+}
+
+type ConditionalEvent struct {
+	Script        string            `json:"script"`
+	ValueVariable string            `json:"value_variable"`
+	Variables     map[string]string `json:"variables"`
+	Qos           int               `json:"qos"`
+	EventId       string            `json:"event_id"`
+	Selection     Selection         `json:"selection"`
+}
+
+type Selection struct {
+	SelectionOptions []SelectionOption `json:"selection_options"`
+}
+
+type SelectionOption struct {
+	Device      *Device      `json:"device"`
+	DeviceGroup *DeviceGroup `json:"device_group"`
+	Services    []Service    `json:"services"`
+}
+
+type Device struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type DeviceGroup struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type Service struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type NotificationList struct {
+	Total         int64          `json:"total"`
+	Limit         int64          `json:"limit"`
+	Offset        int64          `json:"offset"`
+	Notifications []Notification `json:"notifications"`
+}
+
+type Notification struct {
+	Id        string    `json:"_id"`
+	UserId    string    `json:"userId"`
+	Title     string    `json:"title"`
+	Message   string    `json:"message"`
+	IsRead    bool      `json:"isRead"`
+	CreatedAt time.Time `json:"createdAt"`
+	Topic     string    `json:"topic"`
+}
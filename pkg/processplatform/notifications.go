@@ -0,0 +1,43 @@
+/*
+ * Copyright (c) 2023 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package processplatform
+
+import (
+	"net/http"
+	"strconv"
+)
+
+const notificationPageSize = 100
+
+// ListNotifications pages through all notifications of the user
+func (this *Client) ListNotifications(token string) (result []Notification, err error) {
+	for offset := 0; ; offset = offset + notificationPageSize {
+		page := NotificationList{}
+		err = this.do("list_notifications", token, http.MethodGet, this.config.NotificationUrl+"/notifications?limit="+strconv.Itoa(notificationPageSize)+"&offset="+strconv.Itoa(offset), nil, &page)
+		if err != nil {
+			return result, err
+		}
+		result = append(result, page.Notifications...)
+		if len(page.Notifications) < notificationPageSize {
+			return result, nil
+		}
+	}
+}
+
+func (this *Client) DeleteNotifications(token string, ids []string) (err error) {
+	return this.do("delete_notifications", token, http.MethodDelete, this.config.NotificationUrl+"/notifications", ids, nil)
+}
@@ -21,6 +21,7 @@ import (
 	"github.com/SENERGY-Platform/snowflake-canary/pkg/api"
 	"github.com/SENERGY-Platform/snowflake-canary/pkg/canary"
 	"github.com/SENERGY-Platform/snowflake-canary/pkg/configuration"
+	"log"
 	"sync"
 )
 
@@ -29,6 +30,10 @@ func Start(ctx context.Context, wg *sync.WaitGroup, config configuration.Config)
 	if err != nil {
 		return err
 	}
+	err = cmd.CheckOntology()
+	if err != nil {
+		log.Println("WARNING: initial ontology check failed; canary tests are skipped until the check succeeds:", err)
+	}
 	//cmd.StartTests() //initial test
 	return api.Start(ctx, config, cmd)
 }